@@ -2,14 +2,17 @@ package prompt
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 type (
-	BuiltinCommandFunc     func(p *Prompt, command string) (string, tea.Cmd)
+	BuiltinCommandFunc func(p *Prompt, command string) (string, tea.Cmd)
+	// Deprecated: 使用 Command 与 CommandRegistry
 	BuiltinCommandFuncItem struct {
 		Command string             // 命令
 		Desc    string             // 描述
@@ -17,71 +20,286 @@ type (
 	}
 )
 
-// 内置命令方法集合，改为切片结构，便于控制顺序与扩展字段
-var builtinCommandFuncItems = []BuiltinCommandFuncItem{
-	{
-		Command: "/history", // 展示历史命令
-		Desc:    "展示历史命令",
-		Func: func(p *Prompt, command string) (string, tea.Cmd) {
-			outs := make([]string, 0)
-			// 计算右对齐的宽度：使用最大索引的位数（len(historyItems)-1）作为宽度
-			// 例如有 120 条记录，则最大索引为 119，位数为 3，最终以 3 宽度右对齐
-			width := 1
-			if n := len(p.historyItems); n > 0 {
-				width = len(strconv.Itoa(n - 1))
-			}
-			for i, history := range p.historyItems {
-				// 使用动态宽度占位符 %*d 实现右对齐输出索引
-				// 例如：  1 cmd、 23 cmd、123 cmd
-				outs = append(outs, fmt.Sprintf("%*d %s", width, i, history.Command))
-			}
-			return strings.Join(outs, "\n"), Empty
+// defaultCommands 每个 Prompt 默认注册的内置命令
+func defaultCommands() []Command {
+	return []Command{
+		{
+			Name: "/history", // 展示历史命令
+			Desc: "展示历史命令，/history import|export <file> 导入导出历史，/history encrypt [file] 加密历史文件",
+			Args: []Arg{
+				{Name: "count", Desc: "只显示最近的条数，或 import、export、encrypt", Complete: completeHistoryActions},
+				{Name: "file", Desc: "导入导出或加密的文件"},
+			},
+			Flags: []Flag{
+				{Name: "number", Short: "n", Type: ArgInt, Desc: "只显示最近的条数"},
+				{Name: "grep", Short: "g", Desc: "只显示包含指定内容的命令"},
+				{Name: "since", Type: ArgDuration, Desc: "只显示最近一段时间内的命令，如 1h、30m"},
+				{Name: "time", Short: "t", Type: ArgBool, Desc: "显示执行时间"},
+				{Name: "duration", Short: "d", Type: ArgBool, Desc: "显示执行耗时"},
+				{Name: "reverse", Short: "r", Type: ArgBool, Desc: "倒序显示，最新的在前"},
+				{Name: "pager", Short: "p", Type: ArgBool, Desc: "在分页浮层中查看"},
+				{Name: "interactive", Short: "i", Type: ArgBool, Desc: "打开历史浏览器，可过滤、删除与复制"},
+				{Name: "format", Short: "f", Desc: "导入导出的格式，导入时默认自动识别，导出时默认为 zsh", Complete: completeHistoryFormats},
+				{Name: "key-file", Desc: "加密密钥文件，优先使用环境变量 " + HistoryKeyEnv},
+			},
+			Run: historyCommand,
 		},
-	},
-	{
-		Command: "/exit", // 退出程序
-		Desc:    "退出程序",
-		Func: func(p *Prompt, command string) (string, tea.Cmd) {
-			return "", tea.Quit
+		{
+			Name: "/fmt", // 格式化命令
+			Desc: "格式化代码并回填到输入框",
+			Args: []Arg{
+				{Name: "code", Raw: true, Desc: "要格式化的代码，默认为输入框中的内容"},
+			},
+			Run: func(p *Prompt, args *CommandArgs) (string, tea.Cmd) {
+				if args.IsSet("code") {
+					return formatBuiltinCommand(p, args.String("code"))
+				}
+				// 从输入框执行时输入框中是命令本身，这时没有可格式化的代码
+				value := p.Value()
+				if _, isCommand := p.lookupCommand(value); isCommand || strings.TrimSpace(value) == "" {
+					return fmt.Sprintf("fmt: 没有可格式化的代码，使用 /fmt <code> 或按 %s 格式化输入框", p.KeyMap.Format.Help().Key), Empty
+				}
+				return formatBuiltinCommand(p, value)
+			},
 		},
-	},
+		{
+			Name: "/help", // 帮助
+			Desc: "展示命令与快捷键，/help <cmd> 查看命令的详细用法",
+			Args: []Arg{
+				{Name: "command", Desc: "要查看的命令", Complete: completeCommandNames},
+			},
+			Run: helpCommand,
+		},
+		{
+			Name: "/exit", // 退出程序
+			Desc: "退出程序",
+			Run: func(p *Prompt, args *CommandArgs) (string, tea.Cmd) {
+				return "", tea.Quit
+			},
+		},
+	}
 }
 
-// 获取内置方法的补全对象列表
-func GetBuiltinCommandCompletions() []CompletionItem {
-	items := make([]CompletionItem, 0)
-	for _, v := range builtinCommandFuncItems {
-		items = append(items, CompletionItem{
-			Text: v.Command,
-			Desc: v.Desc,
-		})
+func historyCommand(p *Prompt, args *CommandArgs) (string, tea.Cmd) {
+	if args.Bool("interactive") {
+		return "", p.ShowHistoryBrowser()
+	}
+	count := args.Int("number")
+	switch action := args.String("count"); action {
+	case "import", "export":
+		return historyTransferCommand(p, action, args)
+	case "encrypt":
+		return historyEncryptCommand(p, args)
+	case "":
+	default:
+		n, err := strconv.Atoi(action)
+		if err != nil {
+			return fmt.Sprintf("history: 未知操作 %s", action), Empty
+		}
+		count = n
+	}
+
+	p.historyMu.Lock()
+	items := append([]HistoryItem{}, p.historyItems...)
+	p.historyMu.Unlock()
+
+	grep := args.String("grep")
+	var since int64
+	if d := args.Duration("since"); d > 0 {
+		since = time.Now().Add(-d).Unix()
+	}
+	// 保留原始序号，过滤后仍可通过 !n 执行
+	indexes := make([]int, 0, len(items))
+	for i, history := range items {
+		if grep != "" && !strings.Contains(history.Command, grep) {
+			continue
+		}
+		if since > 0 && history.Timestamp < since {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	if count > 0 && count < len(indexes) {
+		indexes = indexes[len(indexes)-count:]
+	}
+	if args.Bool("reverse") {
+		slices.Reverse(indexes)
+	}
+
+	outs := make([]string, 0, len(indexes))
+	// 计算右对齐的宽度：使用最大索引的位数（len(items)-1）作为宽度
+	// 例如有 120 条记录，则最大索引为 119，位数为 3，最终以 3 宽度右对齐
+	width := 1
+	if n := len(items); n > 0 {
+		width = len(strconv.Itoa(n - 1))
+	}
+	durationWidth := 0
+	if args.Bool("duration") {
+		for _, i := range indexes {
+			durationWidth = max(durationWidth, len(formatHistoryDuration(items[i])))
+		}
+	}
+	for _, i := range indexes {
+		// 使用动态宽度占位符 %*d 实现右对齐输出索引
+		// 例如：  1 cmd、 23 cmd、123 cmd
+		line := fmt.Sprintf("%*d ", width, i)
+		if args.Bool("time") {
+			line += formatHistoryTime(items[i]) + "  "
+		}
+		if args.Bool("duration") {
+			line += fmt.Sprintf("%*s  ", durationWidth, formatHistoryDuration(items[i]))
+		}
+		outs = append(outs, line+items[i].Command)
+	}
+	out := strings.Join(outs, "\n")
+	if args.Bool("pager") {
+		return "", p.OpenOverlay(NewPagerOverlay("历史命令", out, p.width, p.height))
+	}
+	return out, Empty
+}
+
+// formatHistoryTime 格式化执行时间，没有时间戳（如从纯 bash 历史导入）时显示为 -
+func formatHistoryTime(item HistoryItem) string {
+	const layout = "2006-01-02 15:04:05"
+	if item.Timestamp <= 0 {
+		return fmt.Sprintf("%-*s", len(layout), "-")
+	}
+	return time.Unix(item.Timestamp, 0).Format(layout)
+}
+
+func formatHistoryDuration(item HistoryItem) string {
+	return (time.Duration(item.DurationSeconds) * time.Second).String()
+}
+
+// historyTransferCommand 处理 /history import 与 /history export
+func historyTransferCommand(p *Prompt, action string, args *CommandArgs) (string, tea.Cmd) {
+	path := args.String("file")
+	if path == "" {
+		return fmt.Sprintf("history: %s 需要文件路径", action), Empty
+	}
+	format := HistoryFormat(args.String("format"))
+	if action == "import" {
+		n, err := p.ImportHistory(path, format)
+		if err != nil {
+			return fmt.Sprintf("history: 导入失败: %v", err), Empty
+		}
+		return fmt.Sprintf("已导入 %d 条历史", n), Empty
+	}
+	n, err := p.ExportHistory(path, format)
+	if err != nil {
+		return fmt.Sprintf("history: 导出失败: %v", err), Empty
+	}
+	return fmt.Sprintf("已导出 %d 条历史到 %s", n, path), Empty
+}
+
+// historyEncryptCommand 处理 /history encrypt，将明文历史文件迁移为加密格式，
+// 迁移的是当前历史文件时改用加密后端
+func historyEncryptCommand(p *Prompt, args *CommandArgs) (string, tea.Cmd) {
+	key, err := LoadHistoryKey(args.String("key-file"))
+	if err != nil {
+		return fmt.Sprintf("history: 读取密钥失败: %v", err), Empty
+	}
+	if key == nil {
+		return fmt.Sprintf("history: 需要设置环境变量 %s 或使用 --key-file 指定密钥文件", HistoryKeyEnv), Empty
+	}
+	current, _ := p.historyStore.(*FileHistoryStore)
+	path := args.String("file")
+	if path == "" {
+		if current == nil {
+			return "history: encrypt 需要文件路径", Empty
+		}
+		path = current.Path()
+	}
+	n, err := EncryptHistoryFile(path, key)
+	if err != nil {
+		return fmt.Sprintf("history: 加密失败: %v", err), Empty
+	}
+	if resolved, _ := resolveHistoryFilePath(path); current != nil && resolved == current.Path() {
+		store, err := NewEncryptedHistoryStore(resolved, key)
+		if err != nil {
+			return fmt.Sprintf("history: 加密失败: %v", err), Empty
+		}
+		p.HistoryStore(store)
+	}
+	return fmt.Sprintf("已加密 %d 条历史", n), Empty
+}
+
+func completeHistoryActions(p *Prompt, prefix string) []CompletionItem {
+	return []CompletionItem{
+		{Text: "import", Desc: "从 bash、zsh、fish 历史文件导入"},
+		{Text: "export", Desc: "导出到文件"},
+		{Text: "encrypt", Desc: "将明文历史文件迁移为加密格式"},
+	}
+}
+
+func completeHistoryFormats(p *Prompt, prefix string) []CompletionItem {
+	formats := HistoryFormats()
+	items := make([]CompletionItem, 0, len(formats))
+	for _, f := range formats {
+		items = append(items, CompletionItem{Text: string(f)})
 	}
 	return items
 }
 
-// AppendBuiltinCommandFunc 添加或覆盖内置命令方法
-func AppendBuiltinCommandFunc(command, desc string, f BuiltinCommandFunc) {
-	// 优先覆盖已存在的命令
-	for i := range builtinCommandFuncItems {
-		if builtinCommandFuncItems[i].Command == command {
-			builtinCommandFuncItems[i].Func = f
-			return
+// formatBuiltinCommand 使用 Prompt 的格式化器（未设置时使用 GoFormatter）格式化代码，
+// 并将结果回填到下一次输入
+func formatBuiltinCommand(p *Prompt, code string) (string, tea.Cmd) {
+	var formatter Formatter = NewGoFormatter()
+	if p.formatter != nil {
+		formatter = p.formatter
+	}
+	formatted, err := formatter.Format(code)
+	if err != nil {
+		return fmt.Sprintf("fmt: %v", err), Empty
+	}
+	p.SetNextValue(formatted)
+	return "", Empty
+}
+
+// legacyCommands 通过已废弃的包级方法注册的命令，所有 Prompt 共享
+var legacyCommands = NewCommandRegistry()
+
+// GetBuiltinCommandCompletions 获取默认内置命令与包级注册命令的补全列表
+//
+// Deprecated: 使用 Prompt.Commands().Completions()
+func GetBuiltinCommandCompletions() []CompletionItem {
+	return commandCompletionItems(withLegacyCommands(DefaultCommandRegistry().Commands(false)))
+}
+
+// withLegacyCommands 追加通过包级方法注册的命令，commands 中已有同名命令时以 commands 为准
+func withLegacyCommands(commands []Command) []Command {
+	names := make(map[string]bool, len(commands))
+	for _, cmd := range commands {
+		names[cmd.FullName()] = true
+	}
+	merged := commands[:len(commands):len(commands)]
+	for _, cmd := range legacyCommands.Commands(false) {
+		if !names[cmd.FullName()] {
+			merged = append(merged, cmd)
 		}
 	}
-	// 不存在则追加
-	builtinCommandFuncItems = append(builtinCommandFuncItems, BuiltinCommandFuncItem{
-		Command: command,
-		Desc:    desc,
-		Func:    f,
-	})
+	return merged
+}
+
+// AppendBuiltinCommandFunc 添加内置命令方法，注册的命令对所有 Prompt 生效，
+// 与 Prompt 自己注册的命令（包括默认命令）同名时以 Prompt 的命令为准
+//
+// Deprecated: 使用 Prompt.Commands().Register 或 WithCommandRegistry
+func AppendBuiltinCommandFunc(command, desc string, f BuiltinCommandFunc) {
+	if err := legacyCommands.Register(Command{Name: command, Desc: desc, Func: f}); err != nil {
+		logger.Warnf("注册内置命令失败: %v", err)
+	}
 }
 
-// IsMatchBuiltinCommandFunc 是否匹配内置命令方法
+// IsMatchBuiltinCommandFunc 是否匹配内置命令方法，匹配规则与 CommandRegistry.Lookup 一致
+//
+// Deprecated: 使用 Prompt.Commands().Lookup
 func IsMatchBuiltinCommandFunc(command string) (BuiltinCommandFunc, bool) {
-	for i := range builtinCommandFuncItems {
-		if builtinCommandFuncItems[i].Command == command {
-			return builtinCommandFuncItems[i].Func, true
-		}
+	if cmd, ok := DefaultCommandRegistry().Lookup(command); ok {
+		return cmd.Exec, true
+	}
+	if cmd, ok := legacyCommands.Lookup(command); ok {
+		return cmd.Exec, true
 	}
 	return nil, false
 }
@@ -0,0 +1,42 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryCommand(t *testing.T) {
+	now := time.Now().Unix()
+	p := NewPrompt(WithHistoryStore(NewMemoryHistoryStore(
+		HistoryItem{Timestamp: now - 7200, DurationSeconds: 65, Command: "a := 1"},
+		HistoryItem{Timestamp: now - 60, DurationSeconds: 2, Command: "fmt.Println(a)"},
+		HistoryItem{Timestamp: now - 30, Command: "b := a"},
+	)))
+	cmd, _ := p.Commands().Lookup("/history")
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"/history", []string{"0 a := 1", "1 fmt.Println(a)", "2 b := a"}},
+		{"/history 1", []string{"2 b := a"}},
+		{"/history -n 2 -r", []string{"2 b := a", "1 fmt.Println(a)"}},
+		{"/history --since 1h -g a", []string{"1 fmt.Println(a)", "2 b := a"}},
+		{"/history -d -n 2", []string{"1 2s  fmt.Println(a)", "2 0s  b := a"}},
+		{"/history -t -n 1", []string{"2 " + time.Unix(now-30, 0).Format("2006-01-02 15:04:05") + "  b := a"}},
+	}
+	for _, tt := range tests {
+		out, _ := cmd.Exec(p, tt.input)
+		if want := strings.Join(tt.want, "\n"); out != want {
+			t.Errorf("%s =\n%s\nwant\n%s", tt.input, out, want)
+		}
+	}
+
+	out, _ := cmd.Exec(p, "/history -p")
+	if out != "" || !p.HasOverlay() {
+		t.Errorf("/history -p should open pager, got %q", out)
+	}
+	if _, ok := p.overlay.(*PagerOverlay); !ok {
+		t.Errorf("overlay = %T, want *PagerOverlay", p.overlay)
+	}
+}
@@ -0,0 +1,172 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/wxnacy/code-prompt/pkg/lsp"
+)
+
+// lspCodeActionExt 保存在 CompletionItem.Ext 中，记录代码操作及其对应的文档快照
+type lspCodeActionExt struct {
+	action   lsp.CodeAction
+	document *LSPDocument
+	input    string
+}
+
+// LSPCodeActions 基于 textDocument/codeAction 的快速修复，
+// List 作为 CodeActionFunc 列出修复项，Apply 作为 CompletionSelectFunc 应用选中的修复
+type LSPCodeActions struct {
+	client  *lsp.LSPClient
+	docFunc LSPDocumentFunc
+	Timeout time.Duration
+	// Only 限定代码操作类型，默认只列出快速修复与整理 import
+	Only []string
+}
+
+func NewLSPCodeActions(client *lsp.LSPClient, docFunc LSPDocumentFunc) *LSPCodeActions {
+	return &LSPCodeActions{
+		client:  client,
+		docFunc: docFunc,
+		Timeout: 3 * time.Second,
+		Only:    []string{lsp.CodeActionQuickFix, lsp.CodeActionSourceOrganizeImports},
+	}
+}
+
+// List 列出当前输入可用的快速修复
+func (a *LSPCodeActions) List(input string, cursor int) []CompletionItem {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	doc, err := a.docFunc(input)
+	if err != nil {
+		logger.Warnf("同步代码操作文档失败: %v", err)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+
+	rng := doc.inputRange(input)
+	// 诊断是异步发布的，先等待当前版本的诊断再请求修复
+	diagnostics, err := a.client.WaitForDiagnostics(ctx, doc.URI)
+	if err != nil {
+		logger.Debugf("等待诊断超时: %v", err)
+	}
+	related := make([]lsp.Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		if lsp.ComparePosition(d.Range.End, rng.Start) >= 0 && lsp.ComparePosition(d.Range.Start, rng.End) <= 0 {
+			related = append(related, d)
+		}
+	}
+
+	actions, err := a.client.CodeActions(ctx, doc.URI, rng, related, a.Only...)
+	if err != nil {
+		logger.Warnf("获取代码操作失败: %v", err)
+		return nil
+	}
+	items := make([]CompletionItem, 0, len(actions))
+	for _, action := range actions {
+		desc := action.Kind
+		if len(action.Diagnostics) > 0 {
+			desc = action.Diagnostics[0].Message
+		}
+		items = append(items, CompletionItem{
+			Text: action.Title,
+			Desc: desc,
+			Ext:  lspCodeActionExt{action: action, document: doc, input: input},
+		})
+	}
+	return items
+}
+
+// Apply 应用选中的快速修复，并用修复后的内容替换输入框
+func (a *LSPCodeActions) Apply(p *Prompt, input string, cursor int, selected CompletionItem) {
+	ext, ok := selected.Ext.(lspCodeActionExt)
+	if !ok {
+		return
+	}
+	newInput, err := a.apply(ext)
+	if err != nil {
+		logger.Warnf("应用代码操作失败: %v", err)
+		return
+	}
+	p.SetValue(newInput)
+	p.SetCursor(len(newInput))
+}
+
+func (a *LSPCodeActions) apply(ext lspCodeActionExt) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+
+	action, err := a.client.ResolveCodeAction(ctx, ext.action)
+	if err != nil {
+		return "", err
+	}
+	edits := make([]lsp.TextEdit, 0)
+	if action.Edit != nil {
+		edits = append(edits, action.Edit.TextEdits()[ext.document.URI]...)
+	}
+	if action.Command != nil {
+		// 命令产生的修改通过 workspace/applyEdit 回传，这里拦截而不是直接写入文档
+		collected := make(chan lsp.WorkspaceEdit, 8)
+		_, err := a.client.ExecuteCommandForDocument(ctx, ext.document.URI, *action.Command, func(edit lsp.WorkspaceEdit) error {
+			select {
+			case collected <- edit:
+				return nil
+			default:
+				return fmt.Errorf("修改过多")
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+	drain:
+		for {
+			select {
+			case edit := <-collected:
+				edits = append(edits, edit.TextEdits()[ext.document.URI]...)
+			default:
+				break drain
+			}
+		}
+	}
+	if len(edits) == 0 {
+		return ext.input, nil
+	}
+	return ext.document.applyEdits(ext.input, edits)
+}
+
+// inputRange 返回输入在文档中的范围
+func (d *LSPDocument) inputRange(input string) lsp.Range {
+	end := d.Start
+	lines := strings.Split(input, "\n")
+	lastUnits := len(utf16.Encode([]rune(lines[len(lines)-1])))
+	if len(lines) == 1 {
+		end.Character += lastUnits
+	} else {
+		end.Line += len(lines) - 1
+		end.Character = lastUnits
+	}
+	return lsp.Range{Start: d.Start, End: end}
+}
+
+// applyEdits 将修改应用到文档，返回修改后输入范围内的内容，
+// 输入范围之外的修改（如新增 import）交给 Apply 回调处理
+func (d *LSPDocument) applyEdits(input string, edits []lsp.TextEdit) (string, error) {
+	newText, err := lsp.ApplyTextEdits(d.Text, edits)
+	if err != nil {
+		return "", err
+	}
+	newRange := lsp.TransformRange(d.inputRange(input), edits)
+	newInput, err := lsp.TextInRange(newText, newRange)
+	if err != nil {
+		return "", err
+	}
+	if d.Apply != nil {
+		d.Apply(newText)
+	}
+	return newInput, nil
+}
@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test: 快速修复在后台获取，输入变化后丢弃过期结果
+func TestPromptCodeActionsAsync(t *testing.T) {
+	var applied string
+	p := NewPrompt(WithCodeActions(
+		func(input string, cursor int) []CompletionItem {
+			return []CompletionItem{{Text: "fix " + input}}
+		},
+		func(p *Prompt, input string, cursor int, selected CompletionItem) {
+			applied = selected.Text
+		},
+	))
+	p.SetValue("x")
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if cmd == nil {
+		t.Fatal("ctrl+o should return a command")
+	}
+	if p.completion != nil {
+		t.Fatal("code actions should not be listed before the command finishes")
+	}
+	p.Update(cmd())
+	if p.completion == nil {
+		t.Fatal("code actions should be listed")
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if applied != "fix x" {
+		t.Errorf("applied = %q, want %q", applied, "fix x")
+	}
+
+	_, cmd = p.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	msg := cmd()
+	p.SetValue("y")
+	p.Update(msg)
+	if p.completion != nil {
+		t.Error("stale code actions should be dropped")
+	}
+}
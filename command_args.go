@@ -0,0 +1,495 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ArgType 参数值的类型，解析时校验
+type ArgType int
+
+const (
+	ArgString ArgType = iota
+	ArgInt
+	ArgBool
+	ArgDuration
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case ArgInt:
+		return "int"
+	case ArgBool:
+		return "bool"
+	case ArgDuration:
+		return "duration"
+	}
+	return "string"
+}
+
+type (
+	// CommandRunFunc 使用解析后的参数执行命令
+	CommandRunFunc func(p *Prompt, args *CommandArgs) (string, tea.Cmd)
+	// ArgCompleteFunc 返回参数的补全候选，prefix 为已输入的部分
+	ArgCompleteFunc func(p *Prompt, prefix string) []CompletionItem
+)
+
+// Arg 位置参数
+type Arg struct {
+	Name     string
+	Desc     string
+	Type     ArgType
+	Default  string
+	Required bool
+	// Variadic 收集剩余的所有位置参数，只能用于最后一个参数
+	Variadic bool
+	// Raw 将剩余的输入原样作为参数值，不做分词，适合代码等包含引号的内容
+	Raw      bool
+	Complete ArgCompleteFunc
+}
+
+// Flag 选项，通过 --name、--name=value 或 -s 传入
+type Flag struct {
+	Name     string // 长名称
+	Short    string // 单字符短名称，可为空
+	Desc     string
+	Type     ArgType
+	Default  string
+	Complete ArgCompleteFunc
+}
+
+// UsageError 参数不符合命令签名
+type UsageError struct {
+	Command Command
+	Err     error
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s: %v\n用法: %s", e.Command.FullName(), e.Err, e.Command.Usage())
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// CommandArgs 解析后的参数，未传入的参数使用默认值
+type CommandArgs struct {
+	values map[string][]string
+	set    map[string]bool
+}
+
+// IsSet 参数或选项是否在输入中出现
+func (a *CommandArgs) IsSet(name string) bool {
+	return a.set[name]
+}
+
+func (a *CommandArgs) String(name string) string {
+	if values := a.values[name]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return ""
+}
+
+// Strings 返回可变参数的所有值
+func (a *CommandArgs) Strings(name string) []string {
+	return a.values[name]
+}
+
+// Int 返回整数参数，解析时已校验格式
+func (a *CommandArgs) Int(name string) int {
+	n, _ := strconv.Atoi(a.String(name))
+	return n
+}
+
+func (a *CommandArgs) Bool(name string) bool {
+	b, _ := strconv.ParseBool(a.String(name))
+	return b
+}
+
+func (a *CommandArgs) Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(a.String(name))
+	return d
+}
+
+// Usage 返回命令的用法，如 /history [-g|--grep value] [count]
+func (c Command) Usage() string {
+	parts := []string{c.FullName()}
+	for _, f := range c.Flags {
+		name := "--" + f.Name
+		if f.Short != "" {
+			name = "-" + f.Short + "|" + name
+		}
+		if f.Type != ArgBool {
+			name += " " + f.Type.String()
+		}
+		parts = append(parts, "["+name+"]")
+	}
+	for _, a := range c.Args {
+		name := a.Name
+		if a.Variadic || a.Raw {
+			name += "..."
+		}
+		if a.Required {
+			parts = append(parts, "<"+name+">")
+		} else {
+			parts = append(parts, "["+name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Exec 执行命令：声明了 Run 的命令先按签名解析参数，参数错误时返回用法说明
+func (c Command) Exec(p *Prompt, input string) (string, tea.Cmd) {
+	if c.Run == nil {
+		return c.Func(p, input)
+	}
+	args, err := c.Parse(input)
+	if err != nil {
+		return err.Error(), Empty
+	}
+	return c.Run(p, args)
+}
+
+// Parse 按 shell 规则分词，并根据命令签名解析输入
+func (c Command) Parse(input string) (*CommandArgs, error) {
+	args := &CommandArgs{values: make(map[string][]string), set: make(map[string]bool)}
+	usageErr := func(format string, a ...interface{}) error {
+		return &UsageError{Command: c, Err: fmt.Errorf(format, a...)}
+	}
+
+	lex := newCommandLexer(input)
+	// 跳过命令名
+	if _, ok, err := lex.next(); err != nil || !ok {
+		return nil, usageErr("缺少命令名")
+	}
+
+	position := 0
+	flagsDone := false
+	for {
+		if position < len(c.Args) && c.Args[position].Raw {
+			if raw := strings.TrimSpace(input[lex.pos:]); raw != "" {
+				args.values[c.Args[position].Name] = []string{raw}
+				args.set[c.Args[position].Name] = true
+			}
+			position++
+			break
+		}
+		tok, ok, err := lex.next()
+		if err != nil {
+			return nil, &UsageError{Command: c, Err: err}
+		}
+		if !ok {
+			break
+		}
+		value := tok.Value
+
+		if !flagsDone && value == "--" && !tok.Quoted {
+			flagsDone = true
+			continue
+		}
+		if !flagsDone && !tok.Quoted && strings.HasPrefix(value, "-") && len(value) > 1 && !isNumber(value) {
+			name, flagValue, hasValue := strings.Cut(strings.TrimLeft(value, "-"), "=")
+			flag, found := c.flag(name)
+			if !found {
+				return nil, usageErr("未知选项 %s", value)
+			}
+			if !hasValue {
+				if flag.Type == ArgBool {
+					flagValue = "true"
+				} else {
+					next, ok, err := lex.next()
+					if err != nil {
+						return nil, &UsageError{Command: c, Err: err}
+					}
+					if !ok {
+						return nil, usageErr("选项 --%s 缺少值", flag.Name)
+					}
+					flagValue = next.Value
+				}
+			}
+			if err := checkArgType(flag.Type, flagValue); err != nil {
+				return nil, usageErr("选项 --%s: %v", flag.Name, err)
+			}
+			args.values[flag.Name] = append(args.values[flag.Name], flagValue)
+			args.set[flag.Name] = true
+			continue
+		}
+
+		if position >= len(c.Args) {
+			return nil, usageErr("多余的参数 %s", value)
+		}
+		arg := c.Args[position]
+		if err := checkArgType(arg.Type, value); err != nil {
+			return nil, usageErr("参数 %s: %v", arg.Name, err)
+		}
+		args.values[arg.Name] = append(args.values[arg.Name], value)
+		args.set[arg.Name] = true
+		if !arg.Variadic {
+			position++
+		}
+	}
+
+	for _, arg := range c.Args {
+		if args.set[arg.Name] {
+			continue
+		}
+		if arg.Required {
+			return nil, usageErr("缺少参数 %s", arg.Name)
+		}
+		if arg.Default != "" {
+			args.values[arg.Name] = []string{arg.Default}
+		}
+	}
+	for _, flag := range c.Flags {
+		if !args.set[flag.Name] && flag.Default != "" {
+			args.values[flag.Name] = []string{flag.Default}
+		}
+	}
+	return args, nil
+}
+
+func (c Command) flag(name string) (Flag, bool) {
+	for _, f := range c.Flags {
+		if f.Name == name || (f.Short != "" && f.Short == name) {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+func checkArgType(typ ArgType, value string) error {
+	var err error
+	switch typ {
+	case ArgInt:
+		_, err = strconv.Atoi(value)
+	case ArgBool:
+		_, err = strconv.ParseBool(value)
+	case ArgDuration:
+		_, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%q 不是有效的 %s", value, typ)
+	}
+	return nil
+}
+
+// isNumber 负数作为参数值而不是选项
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// SplitCommandLine 按 shell 规则分词：空白分隔，支持单引号、双引号与反斜杠转义
+func SplitCommandLine(input string) ([]string, error) {
+	tokens, err := tokenizeCommandLine(input)
+	values := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		values = append(values, tok.Value)
+	}
+	return values, err
+}
+
+// commandToken 分词结果，Start 与 End 为在输入中的字节偏移
+type commandToken struct {
+	Value      string
+	Start, End int
+	// Quoted 包含引号或转义的词不会被当作选项
+	Quoted bool
+}
+
+var errUnterminatedQuote = errors.New("引号未闭合")
+
+// tokenizeCommandLine 返回所有词，遇到未闭合的引号时同时返回已解析的部分与错误
+func tokenizeCommandLine(input string) ([]commandToken, error) {
+	lex := newCommandLexer(input)
+	tokens := make([]commandToken, 0)
+	for {
+		tok, ok, err := lex.next()
+		if ok {
+			tokens = append(tokens, tok)
+		}
+		if err != nil {
+			return tokens, err
+		}
+		if !ok {
+			return tokens, nil
+		}
+	}
+}
+
+type commandLexer struct {
+	input string
+	pos   int
+}
+
+func newCommandLexer(input string) *commandLexer {
+	return &commandLexer{input: input}
+}
+
+// next 读取下一个词，引号未闭合时返回读到结尾的词与错误
+func (l *commandLexer) next() (commandToken, bool, error) {
+	for l.pos < len(l.input) && isCommandSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return commandToken{}, false, nil
+	}
+
+	tok := commandToken{Start: l.pos}
+	var b strings.Builder
+	var quote byte
+	for l.pos < len(l.input) {
+		ch := l.input[l.pos]
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			} else {
+				b.WriteByte(ch)
+			}
+		case quote == '"':
+			if ch == '"' {
+				quote = 0
+			} else if ch == '\\' && l.pos+1 < len(l.input) && strings.IndexByte(`"\$`, l.input[l.pos+1]) >= 0 {
+				l.pos++
+				b.WriteByte(l.input[l.pos])
+			} else {
+				b.WriteByte(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			tok.Quoted = true
+		case ch == '\\' && l.pos+1 < len(l.input):
+			l.pos++
+			b.WriteByte(l.input[l.pos])
+			tok.Quoted = true
+		case isCommandSpace(ch):
+			tok.Value, tok.End = b.String(), l.pos
+			return tok, true, nil
+		default:
+			b.WriteByte(ch)
+		}
+		l.pos++
+	}
+	tok.Value, tok.End = b.String(), l.pos
+	if quote != 0 {
+		return tok, true, errUnterminatedQuote
+	}
+	return tok, true, nil
+}
+
+func isCommandSpace(ch byte) bool {
+	return ch < 0x80 && unicode.IsSpace(rune(ch))
+}
+
+// quoteArg 为包含空白或引号的补全结果加上引号
+func quoteArg(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n'\"\\$") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// argCompletionExt 记录参数补全要替换的输入范围
+type argCompletionExt struct {
+	start, end int
+}
+
+// commandArgCompletions 输入了已注册的命令名之后，根据光标位置补全选项名、选项值或位置参数。
+// 光标仍在命令名上时返回 false，交由命令名补全处理。
+func (m *Prompt) commandArgCompletions(input string, cursor int) ([]CompletionItem, bool) {
+	if cursor > len(input) {
+		cursor = len(input)
+	}
+	cmd, ok := m.lookupCommand(input)
+	if !ok {
+		return nil, false
+	}
+	tokens, err := tokenizeCommandLine(input[:cursor])
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	// 光标前是空白时开始输入新的词
+	current := commandToken{Start: cursor, End: cursor}
+	if err != nil || !isCommandSpace(input[cursor-1]) {
+		current = tokens[len(tokens)-1]
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	// 替换范围延伸到光标后当前词的结尾
+	end := cursor
+	for end < len(input) && !isCommandSpace(input[end]) {
+		end++
+	}
+	ext := argCompletionExt{start: current.Start, end: end}
+
+	var complete ArgCompleteFunc
+	position := 0
+	var pending *Flag
+	for _, tok := range tokens[1:] {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if !tok.Quoted && strings.HasPrefix(tok.Value, "-") && len(tok.Value) > 1 && !isNumber(tok.Value) {
+			name, _, hasValue := strings.Cut(strings.TrimLeft(tok.Value, "-"), "=")
+			if f, found := cmd.flag(name); found && !hasValue && f.Type != ArgBool {
+				pending = &f
+			}
+			continue
+		}
+		if position < len(cmd.Args) && !cmd.Args[position].Variadic {
+			position++
+		}
+	}
+
+	prefix := current.Value
+	items := make([]CompletionItem, 0)
+	switch {
+	case pending != nil:
+		complete = pending.Complete
+	case strings.HasPrefix(prefix, "-") && !current.Quoted:
+		for _, f := range cmd.Flags {
+			items = append(items, CompletionItem{Text: "--" + f.Name, Desc: f.Desc})
+			if f.Short != "" {
+				items = append(items, CompletionItem{Text: "-" + f.Short, Desc: f.Desc})
+			}
+		}
+	case position < len(cmd.Args):
+		complete = cmd.Args[position].Complete
+	}
+	if complete != nil {
+		items = complete(m, prefix)
+	}
+
+	filtered := make([]CompletionItem, 0, len(items))
+	for _, item := range items {
+		if strings.HasPrefix(item.Text, prefix) {
+			item.Ext = ext
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, true
+}
+
+// commandArgSelectFunc 用选中的补全项替换光标所在的参数
+func commandArgSelectFunc(p *Prompt, input string, cursor int, selected CompletionItem) {
+	ext, ok := selected.Ext.(argCompletionExt)
+	if !ok || ext.end > len(input) {
+		return
+	}
+	text := quoteArg(selected.Text)
+	p.SetValue(input[:ext.start] + text + input[ext.end:])
+	p.SetCursor(ext.start + len(text))
+}
@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`/history 20`, []string{"/history", "20"}},
+		{`/get  a   b `, []string{"/get", "a", "b"}},
+		{`/grep 'a b' "c \"d\"" e\ f`, []string{"/grep", "a b", `c "d"`, "e f"}},
+		{`/x 'it'\''s'`, []string{"/x", "it's"}},
+		{`/x ""`, []string{"/x", ""}},
+	}
+	for _, tt := range tests {
+		got, err := SplitCommandLine(tt.input)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommandLine(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := SplitCommandLine(`/x "abc`); !errors.Is(err, errUnterminatedQuote) {
+		t.Errorf("SplitCommandLine() error = %v, want unterminated quote", err)
+	}
+}
+
+func testCommand() Command {
+	return Command{
+		Name: "/log",
+		Args: []Arg{
+			{Name: "count", Type: ArgInt, Default: "10"},
+			{Name: "paths", Variadic: true},
+		},
+		Flags: []Flag{
+			{Name: "grep", Short: "g"},
+			{Name: "all", Short: "a", Type: ArgBool},
+		},
+		Run: func(p *Prompt, args *CommandArgs) (string, tea.Cmd) { return "", nil },
+	}
+}
+
+func TestCommandParse(t *testing.T) {
+	cmd := testCommand()
+
+	args, err := cmd.Parse(`/log -g "fmt x" 5 a b --all`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("grep") != "fmt x" || args.Int("count") != 5 || !args.Bool("all") ||
+		!reflect.DeepEqual(args.Strings("paths"), []string{"a", "b"}) {
+		t.Errorf("Parse() = %+v", args.values)
+	}
+
+	args, err = cmd.Parse(`/log`)
+	if err != nil || args.Int("count") != 10 || args.IsSet("count") {
+		t.Errorf("Parse() default = %+v, %v", args, err)
+	}
+
+	for _, input := range []string{`/log abc`, `/log --unknown`, `/log --grep`, `/log "x`} {
+		var usageErr *UsageError
+		if _, err := cmd.Parse(input); !errors.As(err, &usageErr) {
+			t.Errorf("Parse(%q) error = %v, want UsageError", input, err)
+		}
+	}
+}
+
+func TestCommandParseRaw(t *testing.T) {
+	cmd := Command{Name: "/fmt", Args: []Arg{{Name: "code", Raw: true}}, Run: testCommand().Run}
+	args, err := cmd.Parse(`/fmt fmt.Println("a",  'b')`)
+	if err != nil || args.String("code") != `fmt.Println("a",  'b')` {
+		t.Errorf("Parse() = %q, %v", args.String("code"), err)
+	}
+}
+
+func TestCommandArgCompletions(t *testing.T) {
+	p := NewPrompt(WithCommandRegistry(NewCommandRegistry()))
+	cmd := testCommand()
+	cmd.Flags[0].Complete = func(p *Prompt, prefix string) []CompletionItem {
+		return []CompletionItem{{Text: "fmt"}, {Text: "foo bar"}, {Text: "os"}}
+	}
+	if err := p.Commands().Register(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	items, ok := p.commandArgCompletions("/log --grep f", len("/log --grep f"))
+	if !ok || len(items) != 2 {
+		t.Fatalf("commandArgCompletions() = %v, %v", items, ok)
+	}
+	input := "/log --grep f"
+	commandArgSelectFunc(p, input, len(input), items[1])
+	if p.Value() != "/log --grep 'foo bar'" {
+		t.Errorf("select = %q", p.Value())
+	}
+
+	items, _ = p.commandArgCompletions("/log --a", len("/log --a"))
+	if len(items) != 1 || items[0].Text != "--all" {
+		t.Errorf("flag completions = %v", items)
+	}
+	if _, ok := p.commandArgCompletions("/lo", 3); ok {
+		t.Error("command name should not be completed as argument")
+	}
+}
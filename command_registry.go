@@ -0,0 +1,183 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Command 内置命令。
+// 声明了 Run 的命令按 Args 与 Flags 解析参数后执行，否则将原始输入交给 Func。
+type Command struct {
+	Name string             // 命令名，以 / 开头，如 /history
+	Desc string             // 描述
+	Func BuiltinCommandFunc // 执行方法，接收原始输入
+	// Run 按签名解析参数后执行，设置后优先于 Func
+	Run   CommandRunFunc
+	Args  []Arg
+	Flags []Flag
+	// Aliases 命令别名，如 /h
+	Aliases []string
+	// Hidden 隐藏的命令可以执行，但不出现在补全与帮助中
+	Hidden bool
+	// Namespace 命名空间，非空时通过 /namespace:name 调用
+	Namespace string
+}
+
+// FullName 返回包含命名空间的完整命令名
+func (c Command) FullName() string {
+	return qualifyCommandName(c.Namespace, c.Name)
+}
+
+func qualifyCommandName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return "/" + namespace + ":" + strings.TrimPrefix(name, "/")
+}
+
+// CommandRegistry 内置命令注册表，每个 Prompt 持有独立的实例，可以并发使用
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	commands []*Command          // 按注册顺序保存，用于补全与帮助
+	index    map[string]*Command // 完整命令名与别名到命令的映射
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: make([]*Command, 0),
+		index:    make(map[string]*Command),
+	}
+}
+
+// DefaultCommandRegistry 返回预置了 /history、/fmt、/help 与 /exit 的注册表
+func DefaultCommandRegistry() *CommandRegistry {
+	r := NewCommandRegistry()
+	for _, cmd := range defaultCommands() {
+		if err := r.Register(cmd); err != nil {
+			logger.Warnf("注册内置命令失败: %v", err)
+		}
+	}
+	return r
+}
+
+// Register 注册命令，同名命令会被覆盖。
+// 命令名或别名与其它命令冲突时返回错误。
+func (r *CommandRegistry) Register(cmd Command) error {
+	if !strings.HasPrefix(cmd.Name, "/") {
+		return fmt.Errorf("命令名必须以 / 开头: %s", cmd.Name)
+	}
+	if cmd.Func == nil && cmd.Run == nil {
+		return fmt.Errorf("命令 %s 缺少执行方法", cmd.Name)
+	}
+	for i, arg := range cmd.Args {
+		if (arg.Variadic || arg.Raw) && i != len(cmd.Args)-1 {
+			return fmt.Errorf("命令 %s 的参数 %s 必须是最后一个参数", cmd.Name, arg.Name)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := cmd.FullName()
+	for _, key := range append([]string{name}, r.aliasKeys(cmd)...) {
+		if existing, ok := r.index[key]; ok && existing.FullName() != name {
+			return fmt.Errorf("命令 %s 与 %s 冲突", key, existing.FullName())
+		}
+	}
+
+	c := &cmd
+	if old, ok := r.index[name]; ok {
+		r.unindex(old)
+		for i := range r.commands {
+			if r.commands[i] == old {
+				r.commands[i] = c
+			}
+		}
+	} else {
+		r.commands = append(r.commands, c)
+	}
+	r.index[name] = c
+	for _, key := range r.aliasKeys(cmd) {
+		r.index[key] = c
+	}
+	return nil
+}
+
+// Remove 移除命令，name 可以是完整命令名或别名
+func (r *CommandRegistry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cmd, ok := r.index[name]
+	if !ok {
+		return false
+	}
+	r.unindex(cmd)
+	for i := range r.commands {
+		if r.commands[i] == cmd {
+			r.commands = append(r.commands[:i], r.commands[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Lookup 按输入的第一个单词查找命令，支持别名与 /namespace:name
+func (r *CommandRegistry) Lookup(input string) (Command, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return Command{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.index[fields[0]]
+	if !ok {
+		return Command{}, false
+	}
+	return *cmd, true
+}
+
+// Commands 按注册顺序返回命令，includeHidden 为 false 时不包含隐藏命令
+func (r *CommandRegistry) Commands(includeHidden bool) []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		if cmd.Hidden && !includeHidden {
+			continue
+		}
+		commands = append(commands, *cmd)
+	}
+	return commands
+}
+
+// Completions 返回非隐藏命令的补全列表
+func (r *CommandRegistry) Completions() []CompletionItem {
+	return commandCompletionItems(r.Commands(false))
+}
+
+func commandCompletionItems(commands []Command) []CompletionItem {
+	items := make([]CompletionItem, 0, len(commands))
+	for _, cmd := range commands {
+		items = append(items, CompletionItem{
+			Text: cmd.FullName(),
+			Desc: cmd.Desc,
+		})
+	}
+	return items
+}
+
+func (r *CommandRegistry) aliasKeys(cmd Command) []string {
+	keys := make([]string, 0, len(cmd.Aliases))
+	for _, alias := range cmd.Aliases {
+		keys = append(keys, qualifyCommandName(cmd.Namespace, alias))
+	}
+	return keys
+}
+
+func (r *CommandRegistry) unindex(cmd *Command) {
+	for key, c := range r.index {
+		if c == cmd {
+			delete(r.index, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCommandRegistry(t *testing.T) {
+	noop := func(p *Prompt, command string) (string, tea.Cmd) { return command, nil }
+	r := NewCommandRegistry()
+	if err := r.Register(Command{Name: "/log", Func: noop, Aliases: []string{"/l"}, Namespace: "git"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(Command{Name: "/secret", Func: noop, Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{"/git:log -n 3", "/git:l"} {
+		if cmd, ok := r.Lookup(input); !ok || cmd.FullName() != "/git:log" {
+			t.Errorf("Lookup(%q) = %v, %v", input, cmd.FullName(), ok)
+		}
+	}
+	if _, ok := r.Lookup("/log"); ok {
+		t.Error("namespaced command should not match without namespace")
+	}
+	if _, ok := r.Lookup("/secret"); !ok {
+		t.Error("hidden command should still be callable")
+	}
+	if items := r.Completions(); len(items) != 1 || items[0].Text != "/git:log" {
+		t.Errorf("Completions() = %v", items)
+	}
+	if err := r.Register(Command{Name: "/other", Func: noop, Aliases: []string{"/secret"}}); err == nil {
+		t.Error("Register() expected alias conflict")
+	}
+
+	if !r.Remove("/git:l") {
+		t.Fatal("Remove() by alias failed")
+	}
+	if _, ok := r.Lookup("/git:log"); ok {
+		t.Error("removed command still found")
+	}
+}
+
+func TestPromptCommandsAreIndependent(t *testing.T) {
+	a, b := NewPrompt(), NewPrompt()
+	if _, ok := a.Commands().Lookup("/history"); !ok {
+		t.Fatal("default registry should contain /history")
+	}
+	a.Commands().Remove("/exit")
+	if _, ok := b.Commands().Lookup("/exit"); !ok {
+		t.Error("removing a command from one prompt affected another")
+	}
+}
+
+// Test: 废弃的 IsMatchBuiltinCommandFunc 与 CommandRegistry.Lookup 的匹配规则一致
+func TestIsMatchBuiltinCommandFunc(t *testing.T) {
+	for _, input := range []string{"/history", "/history -n 3", "  /help history"} {
+		if _, ok := IsMatchBuiltinCommandFunc(input); !ok {
+			t.Errorf("IsMatchBuiltinCommandFunc(%q) should match", input)
+		}
+	}
+	for _, input := range []string{"", "/historyx", "history"} {
+		if _, ok := IsMatchBuiltinCommandFunc(input); ok {
+			t.Errorf("IsMatchBuiltinCommandFunc(%q) should not match", input)
+		}
+	}
+}
+
+// Test: 包级方法注册的命令只作为补充，不覆盖 Prompt 自己的命令，补全与帮助中只出现一次
+func TestAppendBuiltinCommandFunc_Fallback(t *testing.T) {
+	AppendBuiltinCommandFunc("/exit", "包级退出", func(p *Prompt, input string) (string, tea.Cmd) {
+		return "legacy exit", Empty
+	})
+	AppendBuiltinCommandFunc("/legacy", "包级命令", func(p *Prompt, input string) (string, tea.Cmd) {
+		return "legacy", Empty
+	})
+	t.Cleanup(func() {
+		legacyCommands.Remove("/exit")
+		legacyCommands.Remove("/legacy")
+	})
+
+	p := NewPrompt()
+	if cmd, ok := p.lookupCommand("/exit"); !ok || cmd.Desc == "包级退出" {
+		t.Errorf("/exit = %+v, %v, want the Prompt's own command", cmd, ok)
+	}
+	if cmd, ok := p.lookupCommand("/legacy"); !ok {
+		t.Error("/legacy not found")
+	} else if out, _ := cmd.Exec(p, "/legacy"); out != "legacy" {
+		t.Errorf("/legacy output = %q", out)
+	}
+
+	counts := make(map[string]int)
+	for _, item := range p.commandCompletions() {
+		counts[item.Text]++
+		if item.Text == "/exit" && item.Desc == "包级退出" {
+			t.Errorf("/exit completion should use the Prompt's own command")
+		}
+	}
+	if counts["/exit"] != 1 || counts["/legacy"] != 1 {
+		t.Errorf("completions = %v, want /exit and /legacy once", counts)
+	}
+	help, _ := p.lookupCommand("/help")
+	if out, _ := help.Exec(p, "/help legacy"); !strings.Contains(out, "/legacy") {
+		t.Errorf("/help legacy = %q", out)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/wxnacy/code-prompt/pkg/lsp"
 )
 
@@ -74,3 +75,35 @@ func TestDefaultCompletionLSPSelectFunc_NonCallable_RemoveParens(t *testing.T) {
 	wantCursor := len(want)
 	assertValueCursor(t, p, want, wantCursor)
 }
+
+type methodSupport map[string]bool
+
+func (s methodSupport) Supports(method string) bool { return s[method] }
+
+// Test: 后端不支持补全时仅跳过补全请求，内置命令补全与导航快捷键仍可用
+func TestPromptWithoutCompletionSupport(t *testing.T) {
+	called := false
+	p := NewPrompt(
+		WithFeatureSupport(methodSupport{}),
+		WithCompletionFunc(func(input string, cursor int) []CompletionItem {
+			called = true
+			return []CompletionItem{{Text: "x"}}
+		}),
+	)
+	for _, b := range []key.Binding{p.KeyMap.NextCompletion, p.KeyMap.PrevCompletion, p.KeyMap.ClearCompletion} {
+		if !b.Enabled() {
+			t.Errorf("binding %v should stay enabled", b.Keys())
+		}
+	}
+	if p.KeyMap.CodeAction.Enabled() {
+		t.Error("code action binding should be disabled")
+	}
+	p.handleCompletion("/he", 3)
+	if p.completion == nil {
+		t.Error("builtin command completions should be listed")
+	}
+	p.handleCompletion("fmt.", 4)
+	if called {
+		t.Error("completion func should not be called")
+	}
+}
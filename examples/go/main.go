@@ -1,24 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"go/ast"
-	"go/format"
 	"go/parser"
 	"go/token"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/sirupsen/logrus"
 	prompt "github.com/wxnacy/code-prompt"
+	"github.com/wxnacy/code-prompt/pkg/goeval"
 	"github.com/wxnacy/code-prompt/pkg/log"
 	"github.com/wxnacy/code-prompt/pkg/lsp"
 	"github.com/wxnacy/code-prompt/pkg/tui"
@@ -26,9 +26,11 @@ import (
 
 var (
 	logger          = log.GetLogger()
-	fileVersion     = 0
 	errCreateLSP    = errors.New("create lsp client")
 	errWaitForReady = errors.New("wait gopls ready")
+
+	// errorStyle 自动打印的 error 值使用红色显示
+	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 )
 
 func main() {
@@ -69,17 +71,58 @@ func main() {
 	_completionFunc := func(input string, cursor int) []prompt.CompletionItem {
 		return completionFunc(input, cursor, client, ctx)
 	}
+	// 高亮、代码操作与格式化使用独立的文档，不与补全和执行共用 main.go
+	docPath := filepath.Join(codeDir, "lsp", "main.go")
+	os.MkdirAll(filepath.Dir(docPath), 0o755)
+	imports := &lspImports{}
+	docFunc := func(input string) (*prompt.LSPDocument, error) {
+		return lspDocument(input, docPath, imports, client, ctx)
+	}
+	codeActions := prompt.NewLSPCodeActions(client, docFunc)
 	p := prompt.NewPrompt()
-	p.HistoryFile(".go_history")
-	p.OutFunc(insertCodeAndRun)
+	p.Highlighter(prompt.NewLSPHighlighter(client, docFunc))
+	p.CodeActions(codeActions.List, codeActions.Apply)
+	p.Formatter(prompt.NewLSPFormatter(client, docFunc))
+	useHistoryFile(p, ".go_history")
+	runner := goeval.NewBuildRunner(codeDir)
+	runner.Sandbox = goeval.DefaultSandbox()
+	session := goeval.NewSession(
+		goeval.WithRunner(runner),
+		goeval.WithErrorStyle(func(text string) string { return errorStyle.Render(text) }),
+	)
+	registerModuleCommands(p.Commands(), runner, client, ctx)
+	p.OutResultFunc(func(input string) prompt.OutResult {
+		imports.importInto(ctx, session)
+		return evalCode(input, session, codePath, client, ctx)
+	})
 	p.CompletionSelectFunc(prompt.DefaultCompletionLSPSelectFunc)
 	p.CompletionFunc(_completionFunc)
+	p.FeatureSupport(client)
 	err = tui.NewTerminal(p).Run()
 	if err != nil {
 		logger.Errorf("go prompt err %v", err)
 	}
 }
 
+// useHistoryFile 设置了历史加密密钥时使用加密的历史文件，明文文件可以通过 /history encrypt 迁移
+func useHistoryFile(p *prompt.Prompt, path string) {
+	key, err := prompt.LoadHistoryKey("")
+	if err != nil {
+		logger.Warnf("读取历史加密密钥失败: %v", err)
+	}
+	if key == nil {
+		p.HistoryFile(path)
+		return
+	}
+	store, err := prompt.NewEncryptedHistoryStore(path, key)
+	if err != nil {
+		logger.Warnf("创建加密历史失败: %v", err)
+		p.HistoryFile(path)
+		return
+	}
+	p.HistoryStore(store)
+}
+
 func prepareLSP(workspace, codePath string) (context.Context, context.CancelFunc, *lsp.LSPClient, error) {
 	// 使用可取消上下文防止长时间运行后被统一超时取消
 	logger.Debugf("创建可取消的上下文")
@@ -92,9 +135,7 @@ func prepareLSP(workspace, codePath string) (context.Context, context.CancelFunc
 		return nil, nil, nil, fmt.Errorf("%w: %w", errCreateLSP, err)
 	}
 
-	fileURI := "file://" + codePath
-	fileVersion++
-	if err := client.DidOpen(ctx, fileURI, "go", fileVersion, ""); err != nil {
+	if err := client.OpenDocument(ctx, codePath, "go", ""); err != nil {
 		logger.Errorf("Initial DidOpen failed: %v", err)
 	}
 
@@ -109,6 +150,81 @@ func prepareLSP(workspace, codePath string) (context.Context, context.CancelFunc
 	return ctx, cancel, client, nil
 }
 
+// lspImports 记录代码操作（如整理 import）新增的 import，
+// 之后同步给 gopls 的文档都会带上，并在下次执行前导入会话
+type lspImports struct {
+	mu      sync.Mutex
+	paths   []string
+	pending []string
+}
+
+// apply 作为 LSPDocument.Apply，从修改后的文档中提取新增的 import
+func (i *lspImports) apply(text string) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", text, parser.ImportsOnly)
+	if err != nil {
+		logger.Warnf("解析代码操作结果失败: %v", err)
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, spec := range file.Imports {
+		if !slices.Contains(i.paths, spec.Path.Value) {
+			i.paths = append(i.paths, spec.Path.Value)
+			i.pending = append(i.pending, spec.Path.Value)
+		}
+	}
+}
+
+// header 返回文档的 package 与 import 部分
+func (i *lspImports) header() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	header := "package main\n\n"
+	if len(i.paths) > 0 {
+		header += "import (\n\t" + strings.Join(i.paths, "\n\t") + "\n)\n\n"
+	}
+	return header
+}
+
+// importInto 将尚未导入的 import 加入会话
+func (i *lspImports) importInto(ctx context.Context, session *goeval.Session) {
+	i.mu.Lock()
+	pending := i.pending
+	i.pending = nil
+	i.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	src := "import (\n\t" + strings.Join(pending, "\n\t") + "\n)"
+	if _, err := session.Eval(ctx, src); err != nil {
+		logger.Warnf("导入代码操作新增的包失败: %v", err)
+	}
+}
+
+// lspDocument 将输入包装到 main 方法中并同步给 gopls，返回输入在文档中的位置。
+// docPath 为独立的文档路径，避免后台高亮请求覆盖补全正在使用的文档
+func lspDocument(input, docPath string, imports *lspImports, client *lsp.LSPClient, ctx context.Context) (*prompt.LSPDocument, error) {
+	tpl := imports.header() + `func main() {
+	%s
+}`
+	code := fmt.Sprintf(tpl, input)
+	// 首次调用时打开文档，之后等同于 ChangeDocument
+	if err := client.OpenDocument(ctx, docPath, "go", code); err != nil {
+		return nil, err
+	}
+	before := tpl[:strings.Index(tpl, "%s")]
+	lines := strings.Split(before, "\n")
+	return &prompt.LSPDocument{
+		URI:  lsp.PathToURI(docPath),
+		Text: code,
+		Start: lsp.Position{
+			Line:      len(lines) - 1,
+			Character: len(lines[len(lines)-1]),
+		},
+		Apply: imports.apply,
+	}, nil
+}
+
 // 补全方法
 // 功能需求:
 // - 根据 input_suffix 和 cursor 光标结合确认补全的索引
@@ -137,7 +253,6 @@ func completionFunc(input string, cursor int, client *lsp.LSPClient, ctx context
 
 	inputAfter := input[cursor:]
 
-	fileVersion++
 	// 根据输入，使用 client 获取补全结果，代码临时存放在 client.fileURI 中
 	tpl := `package main
 
@@ -149,10 +264,14 @@ func main() {
 	code := fmt.Sprintf(tpl, inputBefore+input_suffix+inputAfter)
 
 	// 从 file URI 中获取文件路径
-	filePath := strings.ReplaceAll(client.GetFileURI(), "file://", "")
+	filePath, err := lsp.URIToPath(client.GetFileURI())
+	if err != nil {
+		logger.Errorf("解析文件路径失败: %v", err)
+		return nil
+	}
 	logger.Infof("filePath %s", filePath)
 
-	err := os.WriteFile(filePath, []byte(code), 0o644)
+	err = os.WriteFile(filePath, []byte(code), 0o644)
 	if err != nil {
 		logger.Errorf("写入临时文件失败: %v", err)
 		return nil
@@ -162,9 +281,9 @@ func main() {
 	callCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	err = client.DidOpen(callCtx, client.GetFileURI(), "go", fileVersion, code)
+	err = client.ChangeDocument(callCtx, filePath, code)
 	if err != nil {
-		logger.Errorf("textDocument/didOpen failed: %v", err)
+		logger.Errorf("textDocument/didChange failed: %v", err)
 	}
 
 	// 计算光标位置
@@ -184,6 +303,10 @@ func main() {
 
 	// 获取补全
 	completions, err := client.GetCompletions(callCtx, row, col)
+	if errors.Is(err, lsp.ErrUnsupported) {
+		logger.Debugf("跳过代码补全: %v", err)
+		return nil
+	}
 	if err != nil {
 		logger.Errorf("获取代码补全失败: %v", err)
 		return nil
@@ -210,158 +333,97 @@ func main() {
 	return items
 }
 
-// processCode finds unused variables in the main function of the provided Go code
-// and adds assignments to the blank identifier (_) to make the code compile.
-func processCode(code string) (string, error) {
-	tmpDir, err := os.MkdirTemp("", "go-process-")
-	if err != nil {
-		return "", fmt.Errorf("creating temp dir: %w", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	tmpFile := filepath.Join(tmpDir, "main.go")
-	if err := os.WriteFile(tmpFile, []byte(code), 0o644); err != nil {
-		return "", fmt.Errorf("writing temp file: %w", err)
-	}
-
-	// Initialize a temporary go module.
-	modCmd := exec.Command("go", "mod", "init", "tmpmodule")
-	modCmd.Dir = tmpDir
-	var modErr bytes.Buffer
-	modCmd.Stderr = &modErr
-	if err := modCmd.Run(); err != nil {
-		return "", fmt.Errorf("go mod init failed: %s", modErr.String())
-	}
-
-	// Run 'go build' and capture stderr. We expect it to fail if there are unused vars.
-	cmd := exec.Command("go", "build")
-	cmd.Dir = tmpDir
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Run()
-
-	// Regex to find "declared and not used: var" errors.
-	re := regexp.MustCompile(`(?m)^.*: declared and not used: (\w+)$`)
-	matches := re.FindAllStringSubmatch(stderr.String(), -1)
-
-	var unusedVars []string
-	for _, match := range matches {
-		if len(match) > 1 {
-			unusedVars = append(unusedVars, match[1])
+// registerModuleCommands 注册管理会话依赖的内置命令，go.mod 变化后通知 gopls 重新加载
+func registerModuleCommands(commands *prompt.CommandRegistry, runner *goeval.BuildRunner, client *lsp.LSPClient, ctx context.Context) {
+	modCommand := func(name string, run func(args *prompt.CommandArgs) (string, error)) prompt.CommandRunFunc {
+		return func(p *prompt.Prompt, args *prompt.CommandArgs) (string, tea.Cmd) {
+			out, err := run(args)
+			out = strings.TrimSpace(out)
+			if err != nil {
+				return strings.TrimSpace(fmt.Sprintf("%s: %v\n%s", name, err, out)), prompt.Empty
+			}
+			if err := client.NotifyFileChanged(ctx, lsp.FileChanged, runner.ModFiles()...); err != nil {
+				logger.Warnf("通知文件变更失败: %v", err)
+			}
+			return out, prompt.Empty
 		}
 	}
 
-	if len(unusedVars) == 0 {
-		// No unused variables found, or a different build error occurred.
-		// For this task, we assume other errors are not present and return the original code.
-		return code, nil
-	}
-
-	// Use AST to find the position of the main function's closing brace.
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "", code, 0)
-	if err != nil {
-		return "", fmt.Errorf("parsing code: %w", err)
-	}
-
-	var mainFuncEnd token.Pos = -1
-	ast.Inspect(node, func(n ast.Node) bool {
-		if fn, ok := n.(*ast.FuncDecl); ok && fn.Name.Name == "main" {
-			mainFuncEnd = fn.Body.Rbrace
-			return false // Stop searching
+	for _, cmd := range []prompt.Command{
+		{
+			Name: "/get",
+			Desc: "添加依赖，如 /get github.com/x/y@v1.2.3",
+			Args: []prompt.Arg{
+				{Name: "modules", Required: true, Variadic: true, Desc: "模块路径，可带 @版本"},
+			},
+			Run: modCommand("get", func(args *prompt.CommandArgs) (string, error) {
+				return runner.Get(ctx, args.Strings("modules")...)
+			}),
+		},
+		{
+			Name: "/mod",
+			Desc: "查看 go.mod，/mod tidy 整理依赖",
+			Args: []prompt.Arg{
+				{Name: "action", Desc: "tidy 整理依赖，为空时查看 go.mod", Complete: func(p *prompt.Prompt, prefix string) []prompt.CompletionItem {
+					return []prompt.CompletionItem{{Text: "tidy", Desc: "整理依赖"}}
+				}},
+			},
+			Run: modCommand("mod", func(args *prompt.CommandArgs) (string, error) {
+				switch args.String("action") {
+				case "":
+					return runner.ModFile(ctx)
+				case "tidy":
+					return runner.GoCommand(ctx, "mod", "tidy")
+				}
+				return "", fmt.Errorf("未知操作 %s", args.String("action"))
+			}),
+		},
+		{
+			Name: "/replace",
+			Desc: "替换依赖，如 /replace old=new，/replace --drop old",
+			Args: []prompt.Arg{
+				{Name: "replacement", Required: true, Desc: "old=new，使用 --drop 时为要移除替换的模块"},
+			},
+			Flags: []prompt.Flag{
+				{Name: "drop", Short: "d", Type: prompt.ArgBool, Desc: "移除替换"},
+			},
+			Run: modCommand("replace", func(args *prompt.CommandArgs) (string, error) {
+				if args.Bool("drop") {
+					return runner.DropReplace(ctx, args.String("replacement"))
+				}
+				old, new, ok := strings.Cut(args.String("replacement"), "=")
+				if !ok {
+					return "", errors.New("替换需要使用 old=new 的格式")
+				}
+				return runner.Replace(ctx, old, new)
+			}),
+		},
+	} {
+		if err := commands.Register(cmd); err != nil {
+			logger.Warnf("注册命令失败: %v", err)
 		}
-		return true
-	})
-
-	if mainFuncEnd == -1 {
-		return "", fmt.Errorf("main function not found")
-	}
-
-	// The position is a 1-based offset from the beginning of the file.
-	offset := fset.File(mainFuncEnd).Offset(mainFuncEnd)
-
-	var assignments strings.Builder
-	for _, v := range unusedVars {
-		assignments.WriteString(fmt.Sprintf("\n\t_ = %s", v))
-	}
-
-	// Insert the assignments before the closing brace.
-	newCode := code[:offset] + assignments.String() + "\n" + code[offset:]
-
-	// Format the resulting code for proper indentation.
-	formatted, err := format.Source([]byte(newCode))
-	if err != nil {
-		// If formatting fails, return the unformatted version.
-		return newCode, nil
 	}
-
-	return string(formatted), nil
 }
 
-func insertCodeAndRun(input string) string {
-	curDir, _ := os.Getwd()
-	// defer os.Chdir(curDir)
-	tpl := `package main
-
-
-func main() {
-	// 在这里我们使用fmt包，触发补全
-	%s
-}`
-
-	code := fmt.Sprintf(tpl, input)
-	code, err := processCode(code)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error processing code: %v\n", err)
-	}
-
-	codeDir := filepath.Join(curDir, ".prompt")
-	os.MkdirAll(codeDir, 0o755)
-	codePath := filepath.Join(codeDir, "main.go")
-
-	err = os.WriteFile(codePath, []byte(code), 0o644)
-	if err != nil {
-		fmt.Printf("写入临时文件失败: %v\n", err)
-		panic(err)
-	}
-
-	if _, err := Command("goimports", "-w", codePath); err != nil {
-		fmt.Printf("goimports failed: %v\n", err)
-	}
-	out, err := Command("go", "run", codePath)
-	if err != nil {
-		fmt.Printf("go run failed: %v\n", err)
-		// 即使执行失败，也返回 out
-		return out
-	}
-
-	// out = strings.Trim(out, "\n")
-	// out = fmt.Sprintf("-%s-", out)
-	return out
-}
-
-func Command(name string, args ...string) (string, error) {
-	c := exec.Command(name, args...)
-	var out bytes.Buffer
-	var outErr bytes.Buffer
-	c.Stdout = &out
-	c.Stderr = &outErr
-	err := c.Run()
-
-	outStr := strings.TrimSpace(out.String())
-	errStr := strings.TrimSpace(outErr.String())
-
-	if err != nil {
-		if errStr != "" {
-			return outStr, errors.New(errStr)
+// evalCode 在会话中执行输入，只返回本次输入新增的输出，程序被终止时附带终止原因
+func evalCode(input string, session *goeval.Session, codePath string, client *lsp.LSPClient, ctx context.Context) prompt.OutResult {
+	result, err := session.Eval(ctx, input)
+	out := prompt.OutResult{}
+	var termErr *goeval.TerminationError
+	if errors.As(err, &termErr) {
+		out.Status = termErr.Error()
+		out.Meta = map[string]string{
+			"termination": string(termErr.Reason),
+			"detail":      termErr.Detail,
 		}
-		return outStr, err
+	} else if err != nil {
+		logger.Warnf("执行失败: %v", err)
 	}
-
-	if errStr != "" {
-		// 即使成功，但是 err 有输出，也认为是错误
-		return outStr, errors.New(errStr)
+	logger.Infof("执行耗时: %s", result.Timings)
+	// 通知 gopls 磁盘上的辅助文件已被改写
+	if err := client.NotifyFileChanged(ctx, lsp.FileChanged, codePath); err != nil {
+		logger.Warnf("通知文件变更失败: %v", err)
 	}
-
-	return outStr, nil
+	out.Text = strings.TrimRight(result.Output, "\n")
+	return out
 }
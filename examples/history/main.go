@@ -11,6 +11,7 @@ func main() {
 	p := prompt.NewPrompt(
 		prompt.WithPrompt("> "),
 		prompt.WithHistoryFile("~/.prompt_history"),
+		prompt.WithHistoryOptions(prompt.DefaultHistoryOptions()),
 	)
 	err := tui.NewTerminal(p).Run()
 	if err != nil {
@@ -719,8 +719,8 @@ func main() {
 	}
 
 	// 构建文件URI和工作区URI
-	fileURI := "file://" + tmpFile
-	workspaceURI := "file://" + tmpDir
+	fileURI := lsp.PathToURI(tmpFile)
+	workspaceURI := lsp.PathToURI(tmpDir)
 
 	// 创建带超时的上下文
 	fmt.Println("[DEBUG] 创建带超时的上下文")
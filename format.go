@@ -0,0 +1,124 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"go/format"
+	"strings"
+	"time"
+
+	"github.com/wxnacy/code-prompt/pkg/lsp"
+)
+
+// Formatter 在执行输入与写入历史之前格式化输入
+type Formatter interface {
+	Format(input string) (string, error)
+}
+
+// FormatterFunc 允许使用普通函数作为 Formatter
+type FormatterFunc func(input string) (string, error)
+
+func (f FormatterFunc) Format(input string) (string, error) {
+	return f(input)
+}
+
+// GoFormatter 基于 go/format 的格式化，支持语句列表与声明列表等不完整的代码片段。
+// 输入框只能容纳单行，单行输入格式化后会重新合并为一行。
+type GoFormatter struct{}
+
+func NewGoFormatter() *GoFormatter {
+	return &GoFormatter{}
+}
+
+func (f *GoFormatter) Format(input string) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return input, nil
+	}
+	formatted, err := format.Source([]byte(input))
+	if err != nil {
+		return input, err
+	}
+	return keepSingleLine(input, strings.TrimSpace(string(formatted))), nil
+}
+
+// LSPFormatter 基于 textDocument/rangeFormatting 的格式化，
+// 服务端不支持范围格式化时退回 textDocument/formatting
+type LSPFormatter struct {
+	client  *lsp.LSPClient
+	docFunc LSPDocumentFunc
+	Timeout time.Duration
+	Options lsp.FormattingOptions
+}
+
+func NewLSPFormatter(client *lsp.LSPClient, docFunc LSPDocumentFunc) *LSPFormatter {
+	return &LSPFormatter{
+		client:  client,
+		docFunc: docFunc,
+		Timeout: 3 * time.Second,
+		Options: lsp.DefaultFormattingOptions(),
+	}
+}
+
+func (f *LSPFormatter) Format(input string) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return input, nil
+	}
+	doc, err := f.docFunc(input)
+	if err != nil {
+		return input, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.Timeout)
+	defer cancel()
+
+	edits, err := f.client.RangeFormatting(ctx, doc.URI, doc.inputRange(input), f.Options)
+	if errors.Is(err, lsp.ErrUnsupported) {
+		edits, err = f.client.Formatting(ctx, doc.URI, f.Options)
+	}
+	if err != nil {
+		return input, err
+	}
+	if len(edits) == 0 {
+		return input, nil
+	}
+	formatted, err := doc.applyEdits(input, edits)
+	if err != nil {
+		return input, err
+	}
+	return keepSingleLine(input, strings.TrimSpace(formatted)), nil
+}
+
+// keepSingleLine 原始输入为单行时，将多行的格式化结果合并为等价的单行代码。
+// 含有行注释的结果无法安全合并，此时保留原始输入。
+func keepSingleLine(input, formatted string) string {
+	if strings.Contains(input, "\n") || !strings.Contains(formatted, "\n") {
+		return formatted
+	}
+	for _, t := range NewGoHighlighter().Highlight(formatted) {
+		if t.Kind == TokenComment && strings.HasPrefix(formatted[t.Start:t.End], "//") {
+			return input
+		}
+	}
+	lines := strings.Split(formatted, "\n")
+	var builder strings.Builder
+	prev := ""
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if prev != "" {
+			last := prev[len(prev)-1]
+			switch {
+			case last == '(' || last == '[':
+			case line[0] == ')' || line[0] == ']':
+			case last == '{' || last == ',' || line[0] == '}':
+				builder.WriteString(" ")
+			default:
+				builder.WriteString("; ")
+			}
+		}
+		builder.WriteString(line)
+		prev = line
+	}
+	return builder.String()
+}
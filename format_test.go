@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test: 单行输入格式化后仍保持单行
+func TestGoFormatter_KeepSingleLine(t *testing.T) {
+	cases := map[string]string{
+		"a:=1;b:=2":                        "a := 1; b := 2",
+		"if x{fmt.Println( 1)}else{y()}":   "if x { fmt.Println(1) } else { y() }",
+		"func add(a,b int)int{return a+b}": "func add(a, b int) int { return a + b }",
+		"x := 1 // c":                      "x := 1 // c",
+	}
+	for input, want := range cases {
+		got, err := NewGoFormatter().Format(input)
+		if err != nil {
+			t.Fatalf("Format(%q) error: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("Format(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// Test: 语法错误时返回原始输入
+func TestGoFormatter_InvalidInput(t *testing.T) {
+	input := "a :="
+	got, err := NewGoFormatter().Format(input)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if got != input {
+		t.Fatalf("got %q, want %q", got, input)
+	}
+}
+
+// Test: /fmt 不带参数时格式化输入框中的内容
+func TestFmtCommand_CurrentInput(t *testing.T) {
+	p := NewPrompt()
+	p.AppendHistory("y:=2", "")
+	cmd, _ := p.Commands().Lookup("/fmt")
+
+	p.SetValue("x:=1")
+	if out, _ := cmd.Exec(p, "/fmt"); out != "" {
+		t.Fatalf("/fmt output = %q", out)
+	}
+	if p.nextValue != "x := 1" {
+		t.Errorf("next value = %q, want %q", p.nextValue, "x := 1")
+	}
+
+	p.SetValue("/fmt")
+	if out, _ := cmd.Exec(p, "/fmt"); !strings.Contains(out, "没有可格式化的代码") {
+		t.Errorf("/fmt on command line = %q", out)
+	}
+}
+
+func TestPromptFormatKey(t *testing.T) {
+	p := NewPrompt()
+	p.SetValue("x:=1")
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F"), Alt: true})
+	if got := p.Value(); got != "x := 1" {
+		t.Errorf("value = %q, want %q", got, "x := 1")
+	}
+}
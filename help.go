@@ -0,0 +1,165 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	helpTitleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	helpCommandStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("75"))
+	helpDescStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("246"))
+)
+
+// HelpOverlay 展示内置命令与快捷键的浮层，快捷键使用 bubbles/help 按 FullHelp 的分组展示
+type HelpOverlay struct {
+	Help     help.Model
+	Close    key.Binding
+	commands []Command
+	keyMap   help.KeyMap
+}
+
+func NewHelpOverlay(commands []Command, keyMap help.KeyMap, width int) *HelpOverlay {
+	h := help.New()
+	h.ShowAll = true
+	h.Width = width
+	return &HelpOverlay{
+		Help: h,
+		Close: key.NewBinding(
+			key.WithKeys("esc", "q", "?", "enter"),
+			key.WithHelp("esc/q", "关闭帮助"),
+		),
+		commands: commands,
+		keyMap:   keyMap,
+	}
+}
+
+func (m *HelpOverlay) Init() tea.Cmd {
+	return nil
+}
+
+func (m *HelpOverlay) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if key.Matches(msg, m.Close) {
+			return m, CloseOverlay
+		}
+	case tea.WindowSizeMsg:
+		m.Help.Width = msg.Width
+	}
+	return m, nil
+}
+
+func (m *HelpOverlay) View() string {
+	return BaseFocusStyle.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		renderCommandsHelp(m.commands),
+		"",
+		helpTitleStyle.Render("快捷键"),
+		m.Help.FullHelpView(m.keyMap.FullHelp()),
+		"",
+		m.Help.ShortHelpView([]key.Binding{m.Close}),
+	))
+}
+
+// renderCommandsHelp 列出命令的用法与描述，用法按最长的一条对齐
+func renderCommandsHelp(commands []Command) string {
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, lipgloss.Width(cmd.Usage()))
+	}
+	lines := []string{helpTitleStyle.Render("命令")}
+	for _, cmd := range commands {
+		usage := cmd.Usage()
+		padding := strings.Repeat(" ", width-lipgloss.Width(usage))
+		lines = append(lines, "  "+helpCommandStyle.Render(usage)+padding+"  "+helpDescStyle.Render(cmd.Desc))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Help 返回命令的详细用法
+func (c Command) Help() string {
+	lines := []string{"用法: " + c.Usage()}
+	if c.Desc != "" {
+		lines = append(lines, c.Desc)
+	}
+	if len(c.Aliases) > 0 {
+		aliases := make([]string, 0, len(c.Aliases))
+		for _, alias := range c.Aliases {
+			aliases = append(aliases, qualifyCommandName(c.Namespace, alias))
+		}
+		lines = append(lines, "别名: "+strings.Join(aliases, ", "))
+	}
+	if len(c.Args) > 0 {
+		lines = append(lines, "参数:")
+		for _, arg := range c.Args {
+			line := fmt.Sprintf("  %s  %s  %s", arg.Name, arg.Type, arg.Desc)
+			if arg.Required {
+				line += " (必填)"
+			}
+			if arg.Default != "" {
+				line += fmt.Sprintf(" (默认: %s)", arg.Default)
+			}
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+	}
+	if len(c.Flags) > 0 {
+		lines = append(lines, "选项:")
+		for _, flag := range c.Flags {
+			name := "--" + flag.Name
+			if flag.Short != "" {
+				name = "-" + flag.Short + ", " + name
+			}
+			line := fmt.Sprintf("  %s  %s  %s", name, flag.Type, flag.Desc)
+			if flag.Default != "" {
+				line += fmt.Sprintf(" (默认: %s)", flag.Default)
+			}
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// allCommands 返回当前 Prompt 可见的所有命令，包括通过包级方法注册的命令
+func (m *Prompt) allCommands() []Command {
+	return withLegacyCommands(m.commands.Commands(false))
+}
+
+// ShowHelp 打开帮助浮层
+func (m *Prompt) ShowHelp() tea.Cmd {
+	return m.OpenOverlay(NewHelpOverlay(m.allCommands(), m.KeyMap, m.width))
+}
+
+// helpCommand /help 列出所有命令与快捷键，/help <cmd> 展示命令的详细用法
+func helpCommand(p *Prompt, args *CommandArgs) (string, tea.Cmd) {
+	if name := args.String("command"); name != "" {
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		cmd, ok := p.lookupCommand(name)
+		if !ok {
+			return fmt.Sprintf("help: 未知命令 %s", name), Empty
+		}
+		return cmd.Help(), Empty
+	}
+	h := help.New()
+	h.ShowAll = true
+	h.Width = p.width
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		renderCommandsHelp(p.allCommands()),
+		"",
+		helpTitleStyle.Render("快捷键"),
+		h.FullHelpView(p.KeyMap.FullHelp()),
+	), Empty
+}
+
+// completeCommandNames 补全命令名，用于 /help 的参数
+func completeCommandNames(p *Prompt, prefix string) []CompletionItem {
+	return p.commandCompletions()
+}
@@ -0,0 +1,47 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHelpOverlay(t *testing.T) {
+	p := NewPrompt()
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if !p.HasOverlay() {
+		t.Fatal("? on empty input should open help")
+	}
+	if view := p.View(); !strings.Contains(view, "/history") || !strings.Contains(view, "ctrl+l") {
+		t.Errorf("help view missing commands or keys:\n%s", view)
+	}
+
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("esc should return close command")
+	}
+	p.Update(cmd())
+	if p.HasOverlay() {
+		t.Error("help should be closed")
+	}
+
+	p.SetValue("a")
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if p.HasOverlay() {
+		t.Error("? with input should be typed, not open help")
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	p := NewPrompt()
+	cmd, _ := p.Commands().Lookup("/help")
+	out, _ := cmd.Exec(p, "/help history")
+	if !strings.Contains(out, "用法: /history [-n|--number int] [-g|--grep string]") || !strings.Contains(out, "--grep") {
+		t.Errorf("/help history =\n%s", out)
+	}
+	out, _ = cmd.Exec(p, "/help /nope")
+	if !strings.Contains(out, "未知命令") {
+		t.Errorf("/help /nope = %s", out)
+	}
+}
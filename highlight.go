@@ -0,0 +1,155 @@
+package prompt
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TokenKind 语法高亮的标记类型
+type TokenKind int
+
+const (
+	TokenPlain TokenKind = iota
+	TokenKeyword
+	TokenString
+	TokenNumber
+	TokenComment
+	TokenOperator
+	TokenFunction
+	TokenType
+	TokenParameter
+	TokenVariable
+	TokenNamespace
+	TokenConstant
+	TokenProperty
+)
+
+// Token 表示输入中的一段高亮区间，Start 与 End 为字节偏移（左闭右开）
+type Token struct {
+	Start int
+	End   int
+	Kind  TokenKind
+}
+
+// Highlighter 将输入切分为带类型的高亮区间，区间之间不应重叠
+type Highlighter interface {
+	Highlight(input string) []Token
+}
+
+// HighlighterFunc 允许使用普通函数作为 Highlighter
+type HighlighterFunc func(input string) []Token
+
+func (f HighlighterFunc) Highlight(input string) []Token {
+	return f(input)
+}
+
+// AsyncHighlighter 需要通过网络或子进程获取高亮的实现，如 LSP 语义高亮。
+// Highlight 只读取缓存，不应阻塞渲染；输入变化后 Prompt 调用 HighlightCmd 在后台获取，
+// 完成后返回 HighlightedMsg 触发重新渲染
+type AsyncHighlighter interface {
+	Highlighter
+	HighlightCmd(input string) tea.Cmd
+}
+
+// HighlightedMsg 异步高亮获取完成
+type HighlightedMsg struct {
+	Input string
+}
+
+// renderHighlighted 按高亮区间渲染文本，cursor 为光标所在的 rune 下标，
+// renderCursor 负责渲染光标所在字符，cursor 超出文本长度时渲染一个空格
+func renderHighlighted(input string, tokens []Token, theme Theme, cursor int, renderCursor func(char string, kind TokenKind) string) string {
+	// 排序副本，避免修改 Highlighter 缓存的切片
+	tokens = append([]Token(nil), tokens...)
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Start < tokens[j].Start
+	})
+
+	var (
+		builder strings.Builder
+		segment strings.Builder
+		kind    = TokenPlain
+		ti      = 0
+		index   = 0
+	)
+	flush := func() {
+		if segment.Len() == 0 {
+			return
+		}
+		builder.WriteString(theme.Style(kind).Inline(true).Render(segment.String()))
+		segment.Reset()
+	}
+
+	for offset, r := range input {
+		for ti < len(tokens) && tokens[ti].End <= offset {
+			ti++
+		}
+		runeKind := TokenPlain
+		if ti < len(tokens) && tokens[ti].Start <= offset {
+			runeKind = tokens[ti].Kind
+		}
+		if index == cursor {
+			flush()
+			builder.WriteString(renderCursor(string(r), runeKind))
+			kind = TokenPlain
+		} else {
+			if runeKind != kind {
+				flush()
+				kind = runeKind
+			}
+			segment.WriteRune(r)
+		}
+		index++
+	}
+	flush()
+	if cursor >= index {
+		builder.WriteString(renderCursor(" ", TokenPlain))
+	}
+	return builder.String()
+}
+
+// mergeTokens 以 primary 为准合并高亮区间，secondary 中与 primary 重叠的区间会被丢弃
+func mergeTokens(primary, secondary []Token) []Token {
+	merged := make([]Token, 0, len(primary)+len(secondary))
+	merged = append(merged, primary...)
+	for _, t := range secondary {
+		overlap := false
+		for _, p := range primary {
+			if t.Start < p.End && p.Start < t.End {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
+
+// utf16ToByteOffset 将一行文本中的 UTF-16 偏移（LSP 默认编码）转换为字节偏移
+func utf16ToByteOffset(line string, units int) int {
+	count := 0
+	for offset, r := range line {
+		if count >= units {
+			return offset
+		}
+		count += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// byteToUTF16Offset 将一行文本中的字节偏移转换为 UTF-16 偏移
+func byteToUTF16Offset(line string, offset int) int {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	units := 0
+	for _, r := range line[:offset] {
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"go/scanner"
+	"go/token"
+)
+
+// Go 预声明的类型与常量
+var (
+	goPredeclaredTypes = map[string]bool{
+		"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+		"complex128": true, "error": true, "float32": true, "float64": true, "int": true,
+		"int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+		"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+		"uint64": true, "uintptr": true,
+	}
+	goPredeclaredConstants = map[string]bool{
+		"true": true, "false": true, "nil": true, "iota": true,
+	}
+)
+
+// GoHighlighter 基于 go/scanner 的词法高亮，不依赖外部进程，适合作为默认或兜底实现
+type GoHighlighter struct{}
+
+func NewGoHighlighter() *GoHighlighter {
+	return &GoHighlighter{}
+}
+
+func (h *GoHighlighter) Highlight(input string) []Token {
+	src := []byte(input)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	// 输入通常是不完整的代码片段，忽略词法错误
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+
+	type scanned struct {
+		offset int
+		tok    token.Token
+		lit    string
+	}
+	items := make([]scanned, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// 跳过自动插入的分号
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		items = append(items, scanned{offset: file.Offset(pos), tok: tok, lit: lit})
+	}
+
+	tokens := make([]Token, 0, len(items))
+	for i, item := range items {
+		length := len(item.lit)
+		if length == 0 {
+			length = len(item.tok.String())
+		}
+		t := Token{Start: item.offset, End: item.offset + length}
+		switch {
+		case item.tok.IsKeyword():
+			t.Kind = TokenKeyword
+		case item.tok == token.STRING || item.tok == token.CHAR:
+			t.Kind = TokenString
+		case item.tok == token.INT || item.tok == token.FLOAT || item.tok == token.IMAG:
+			t.Kind = TokenNumber
+		case item.tok == token.COMMENT:
+			t.Kind = TokenComment
+		case item.tok == token.IDENT:
+			switch {
+			case i+1 < len(items) && items[i+1].tok == token.LPAREN:
+				t.Kind = TokenFunction
+			case goPredeclaredTypes[item.lit]:
+				t.Kind = TokenType
+			case goPredeclaredConstants[item.lit]:
+				t.Kind = TokenConstant
+			case i+1 < len(items) && items[i+1].tok == token.PERIOD:
+				t.Kind = TokenNamespace
+			case i > 0 && items[i-1].tok == token.PERIOD:
+				t.Kind = TokenProperty
+			default:
+				t.Kind = TokenVariable
+			}
+		case item.tok.IsOperator():
+			t.Kind = TokenOperator
+		default:
+			continue
+		}
+		if t.End > len(src) {
+			t.End = len(src)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
@@ -0,0 +1,199 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wxnacy/code-prompt/pkg/lsp"
+)
+
+// LSPDocument 描述输入在 LSP 文档中的位置
+type LSPDocument struct {
+	URI   string       // 文档 URI
+	Text  string       // 文档完整内容
+	Start lsp.Position // 输入在文档中的起始位置，Character 以 UTF-16 编码单元计
+
+	// Apply 可选，代码操作或格式化修改了输入范围之外的内容（如新增 import）时以新文档内容回调
+	Apply func(text string)
+}
+
+// LSPDocumentFunc 将输入包装为完整文档并同步给 LSP 服务端（通常通过 ChangeDocument），
+// 返回输入在文档中的位置。高亮、代码操作、格式化等 LSP 功能共用该方法。
+type LSPDocumentFunc func(input string) (*LSPDocument, error)
+
+// highlightCacheSize 语义高亮缓存的最大条目数，超出后清空重建
+const highlightCacheSize = 128
+
+// LSPHighlighter 基于 textDocument/semanticTokens/full 的高亮实现，
+// 语义标记未覆盖的部分、请求尚未返回以及服务端不可用时由 Fallback 补充。
+// 语义标记通过 HighlightCmd 在后台获取并按输入缓存，Highlight 不会阻塞渲染
+type LSPHighlighter struct {
+	client   *lsp.LSPClient
+	docFunc  LSPDocumentFunc
+	Fallback Highlighter
+	Timeout  time.Duration
+
+	mu       sync.Mutex
+	cache    map[string][]Token
+	pending  string
+	disabled bool
+
+	lexicalInput string
+	lexicalToken []Token
+
+	// fetchMu 保证同一时间只有一个请求在同步文档
+	fetchMu sync.Mutex
+}
+
+func NewLSPHighlighter(client *lsp.LSPClient, docFunc LSPDocumentFunc) *LSPHighlighter {
+	return &LSPHighlighter{
+		client:   client,
+		docFunc:  docFunc,
+		Fallback: NewGoHighlighter(),
+		Timeout:  time.Second,
+		cache:    make(map[string][]Token),
+	}
+}
+
+// Highlight 返回缓存的语义高亮，尚未获取时返回 Fallback 的结果
+func (h *LSPHighlighter) Highlight(input string) []Token {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if tokens, ok := h.cache[input]; ok {
+		return tokens
+	}
+	return h.lexical(input)
+}
+
+// HighlightCmd 在后台获取 input 的语义高亮，已缓存、服务端不支持或输入为空时返回 nil。
+// 连续输入时只有最新的输入会真正发送请求
+func (h *LSPHighlighter) HighlightCmd(input string) tea.Cmd {
+	h.mu.Lock()
+	_, cached := h.cache[input]
+	if cached || h.disabled || strings.TrimSpace(input) == "" {
+		h.mu.Unlock()
+		return nil
+	}
+	h.pending = input
+	h.mu.Unlock()
+
+	return func() tea.Msg {
+		h.fetchMu.Lock()
+		defer h.fetchMu.Unlock()
+		if !h.isPending(input) {
+			return nil
+		}
+		semantic, err := h.semanticTokens(input)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		switch {
+		case errors.Is(err, lsp.ErrUnsupported):
+			logger.Debugf("语义高亮不可用，使用词法高亮: %v", err)
+			h.disabled = true
+			return nil
+		case err != nil:
+			logger.Warnf("获取语义高亮失败: %v", err)
+			return nil
+		}
+		if h.cache == nil || len(h.cache) >= highlightCacheSize {
+			h.cache = make(map[string][]Token)
+		}
+		h.cache[input] = mergeTokens(semantic, h.lexical(input))
+		return HighlightedMsg{Input: input}
+	}
+}
+
+func (h *LSPHighlighter) isPending(input string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.pending == input
+}
+
+// lexical 返回 Fallback 的高亮结果，View 会被频繁调用，因此缓存最近一次输入，调用方需持有 mu
+func (h *LSPHighlighter) lexical(input string) []Token {
+	if h.Fallback == nil {
+		return nil
+	}
+	if h.lexicalToken == nil || h.lexicalInput != input {
+		h.lexicalInput = input
+		h.lexicalToken = h.Fallback.Highlight(input)
+	}
+	return h.lexicalToken
+}
+
+func (h *LSPHighlighter) semanticTokens(input string) ([]Token, error) {
+	if h.client == nil || h.docFunc == nil {
+		return nil, &lsp.UnsupportedError{Method: lsp.MethodSemanticTokensFull}
+	}
+	doc, err := h.docFunc(input)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	result, err := h.client.SemanticTokensFull(ctx, doc.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(doc.Text, "\n")
+	if doc.Start.Line >= len(lines) {
+		return nil, nil
+	}
+	lineText := lines[doc.Start.Line]
+	inputStart := utf16ToByteOffset(lineText, doc.Start.Character)
+
+	tokens := make([]Token, 0)
+	for _, st := range lsp.DecodeSemanticTokens(result.Data, h.client.SemanticTokenLegend()) {
+		if st.Line != doc.Start.Line || st.Character < doc.Start.Character {
+			continue
+		}
+		start := utf16ToByteOffset(lineText, st.Character) - inputStart
+		end := utf16ToByteOffset(lineText, st.Character+st.Length) - inputStart
+		if start < 0 || end > len(input) || start >= end {
+			continue
+		}
+		tokens = append(tokens, Token{Start: start, End: end, Kind: semanticTokenKind(st)})
+	}
+	return tokens, nil
+}
+
+// semanticTokenKind 将 LSP 语义标记类型映射为高亮类型
+func semanticTokenKind(t lsp.SemanticToken) TokenKind {
+	switch t.Type {
+	case "namespace":
+		return TokenNamespace
+	case "type", "class", "enum", "interface", "struct", "typeParameter":
+		return TokenType
+	case "parameter":
+		return TokenParameter
+	case "variable":
+		if t.HasModifier("readonly") {
+			return TokenConstant
+		}
+		return TokenVariable
+	case "property":
+		return TokenProperty
+	case "enumMember":
+		return TokenConstant
+	case "function", "method", "macro":
+		return TokenFunction
+	case "keyword", "modifier":
+		return TokenKeyword
+	case "comment":
+		return TokenComment
+	case "string", "regexp":
+		return TokenString
+	case "number":
+		return TokenNumber
+	case "operator":
+		return TokenOperator
+	default:
+		return TokenPlain
+	}
+}
@@ -0,0 +1,142 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRenderHighlighted(t *testing.T) {
+	input := `fmt.Println("中文")`
+	tokens := []Token{
+		{Start: 12, End: 20, Kind: TokenString},
+		{Start: 0, End: 3, Kind: TokenNamespace},
+		{Start: 4, End: 11, Kind: TokenFunction},
+	}
+	original := append([]Token(nil), tokens...)
+
+	var cursorKind TokenKind
+	renderCursor := func(char string, kind TokenKind) string {
+		cursorKind = kind
+		return "[" + char + "]"
+	}
+	// cursor 为 rune 下标，指向“文”
+	got := renderHighlighted(input, tokens, DefaultTheme(), 14, renderCursor)
+	if want := `fmt.Println("中[文]")`; got != want {
+		t.Errorf("renderHighlighted = %q, want %q", got, want)
+	}
+	if cursorKind != TokenString {
+		t.Errorf("cursor kind = %v, want TokenString", cursorKind)
+	}
+	if !reflect.DeepEqual(tokens, original) {
+		t.Errorf("renderHighlighted modified tokens: %v", tokens)
+	}
+
+	got = renderHighlighted("ab", nil, DefaultTheme(), 2, renderCursor)
+	if got != "ab[ ]" || cursorKind != TokenPlain {
+		t.Errorf("cursor at end = %q (%v)", got, cursorKind)
+	}
+}
+
+func TestGoHighlighter(t *testing.T) {
+	input := `x := fmt.Sprint(len(s), p.Name, "a", 1) // c`
+	want := map[string]TokenKind{
+		"x":      TokenVariable,
+		":=":     TokenOperator,
+		"fmt":    TokenNamespace,
+		"Sprint": TokenFunction,
+		"Name":   TokenProperty,
+		"len":    TokenFunction,
+		`"a"`:    TokenString,
+		"1":      TokenNumber,
+		"// c":   TokenComment,
+		"(":      TokenOperator,
+		"s":      TokenVariable,
+	}
+	got := make(map[string]TokenKind)
+	for _, tok := range NewGoHighlighter().Highlight(input) {
+		got[input[tok.Start:tok.End]] = tok.Kind
+	}
+	for text, kind := range want {
+		if got[text] != kind {
+			t.Errorf("%q kind = %v, want %v", text, got[text], kind)
+		}
+	}
+
+	for _, tok := range NewGoHighlighter().Highlight("for i := range nil {") {
+		if tok.Start == 0 && tok.Kind != TokenKeyword {
+			t.Errorf("for kind = %v, want TokenKeyword", tok.Kind)
+		}
+		if tok.End > len("for i := range nil {") {
+			t.Errorf("token out of range: %+v", tok)
+		}
+	}
+}
+
+func TestUTF16Offsets(t *testing.T) {
+	line := "a中😀b"
+	tests := []struct {
+		units int
+		bytes int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 4},
+		{4, 8},
+		{5, 9},
+		{10, 9},
+	}
+	for _, tt := range tests {
+		if got := utf16ToByteOffset(line, tt.units); got != tt.bytes {
+			t.Errorf("utf16ToByteOffset(%d) = %d, want %d", tt.units, got, tt.bytes)
+		}
+		if tt.units <= 5 {
+			if got := byteToUTF16Offset(line, tt.bytes); got != tt.units {
+				t.Errorf("byteToUTF16Offset(%d) = %d, want %d", tt.bytes, got, tt.units)
+			}
+		}
+	}
+	if got := byteToUTF16Offset(line, 100); got != 5 {
+		t.Errorf("byteToUTF16Offset past end = %d, want 5", got)
+	}
+}
+
+// Test: 语义高亮不可用时 HighlightCmd 不再发送请求，Highlight 使用词法高亮
+func TestLSPHighlighter_Unsupported(t *testing.T) {
+	h := NewLSPHighlighter(nil, nil)
+	input := "x := 1"
+	if tokens := h.Highlight(input); len(tokens) == 0 {
+		t.Fatal("Highlight should fall back to lexical tokens")
+	}
+	cmd := h.HighlightCmd(input)
+	if cmd == nil {
+		t.Fatal("HighlightCmd should return a command before the first request")
+	}
+	if msg := cmd(); msg != nil {
+		t.Errorf("unsupported server should not emit %T", msg)
+	}
+	if h.HighlightCmd("y := 2") != nil {
+		t.Error("HighlightCmd should be disabled after ErrUnsupported")
+	}
+}
+
+// Test: 输入变化时 Prompt 返回异步高亮命令
+func TestPromptHighlightCmd(t *testing.T) {
+	var requested []string
+	p := NewPrompt(WithHighlighter(asyncHighlighterFunc(func(input string) tea.Cmd {
+		requested = append(requested, input)
+		return func() tea.Msg { return HighlightedMsg{Input: input} }
+	})))
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	p.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if !reflect.DeepEqual(requested, []string{"x"}) {
+		t.Errorf("requested = %v, want [x]", requested)
+	}
+}
+
+type asyncHighlighterFunc func(input string) tea.Cmd
+
+func (f asyncHighlighterFunc) Highlight(input string) []Token { return nil }
+
+func (f asyncHighlighterFunc) HighlightCmd(input string) tea.Cmd { return f(input) }
@@ -0,0 +1,231 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wxnacy/code-prompt/pkg/tui"
+)
+
+// HistorySelectedMsg 在历史浏览器中选中了命令，Prompt 收到后将命令填入输入框
+type HistorySelectedMsg struct {
+	Command string
+}
+
+// historyListItem 历史浏览器中的一项，index 为历史序号，与 /history 和 !n 一致
+type historyListItem struct {
+	index int
+	item  HistoryItem
+}
+
+func (i historyListItem) FilterValue() string {
+	return i.item.Command
+}
+
+func (i historyListItem) Title() string {
+	command, _, multiline := strings.Cut(i.item.Command, "\n")
+	if multiline {
+		command += " …"
+	}
+	return fmt.Sprintf("%d  %s", i.index, command)
+}
+
+func (i historyListItem) Description() string {
+	return strings.TrimSpace(formatHistoryTime(i.item)) + "  耗时 " + formatHistoryDuration(i.item)
+}
+
+// HistoryBrowserKeyMap 历史浏览器的快捷键，列表的移动与过滤使用 list.DefaultKeyMap
+type HistoryBrowserKeyMap struct {
+	Select key.Binding
+	Delete key.Binding
+	Yank   key.Binding
+	Close  key.Binding
+}
+
+func DefaultHistoryBrowserKeyMap() HistoryBrowserKeyMap {
+	return HistoryBrowserKeyMap{
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "填入输入框"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "删除"),
+		),
+		Yank: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "复制"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "关闭"),
+		),
+	}
+}
+
+// HistoryBrowser 可过滤的历史列表，最新的命令在最前，实现 tui.Model 以便单独运行。
+// 作为浮层使用时通过 Prompt.ShowHistoryBrowser 打开。
+type HistoryBrowser struct {
+	List   list.Model
+	KeyMap HistoryBrowserKeyMap
+	// OnDelete 删除历史项，为 nil 时不支持删除
+	OnDelete func(item HistoryItem) error
+
+	action  string
+	payload any
+}
+
+var _ tui.Model = (*HistoryBrowser)(nil)
+
+// NewHistoryBrowser 创建历史浏览器，items 按时间顺序排列
+func NewHistoryBrowser(items []HistoryItem, width, height int) *HistoryBrowser {
+	if height <= 0 {
+		height = defaultPagerHeight
+	}
+	listItems := make([]list.Item, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		listItems = append(listItems, historyListItem{index: i, item: items[i]})
+	}
+	l := list.New(listItems, list.NewDefaultDelegate(), width, height)
+	l.Title = "历史命令"
+	l.SetStatusBarItemName("条", "条")
+	l.DisableQuitKeybindings()
+	m := &HistoryBrowser{List: l, KeyMap: DefaultHistoryBrowserKeyMap()}
+	m.List.AdditionalShortHelpKeys = m.helpKeys
+	m.List.AdditionalFullHelpKeys = m.helpKeys
+	return m
+}
+
+func (m *HistoryBrowser) helpKeys() []key.Binding {
+	return []key.Binding{m.KeyMap.Select, m.KeyMap.Delete, m.KeyMap.Yank, m.KeyMap.Close}
+}
+
+func (m *HistoryBrowser) Init() tea.Cmd {
+	return nil
+}
+
+func (m *HistoryBrowser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		// 输入过滤条件时按键交给列表
+		if m.List.SettingFilter() {
+			break
+		}
+		selected, hasSelected := m.List.SelectedItem().(historyListItem)
+		switch {
+		case key.Matches(msg, m.KeyMap.Close) && !(msg.String() == "esc" && m.List.IsFiltered()):
+			// 已过滤时 esc 先清除过滤条件
+			return m, CloseOverlay
+		case key.Matches(msg, m.KeyMap.Select) && hasSelected:
+			m.action, m.payload = "select", selected.item.Command
+			command := selected.item.Command
+			return m, tea.Batch(CloseOverlay, func() tea.Msg {
+				return HistorySelectedMsg{Command: command}
+			})
+		case key.Matches(msg, m.KeyMap.Delete) && hasSelected && m.OnDelete != nil:
+			if err := m.OnDelete(selected.item); err != nil {
+				return m, m.List.NewStatusMessage(fmt.Sprintf("删除失败: %v", err))
+			}
+			m.remove(selected)
+			return m, m.List.NewStatusMessage("已删除")
+		case key.Matches(msg, m.KeyMap.Yank) && hasSelected:
+			if err := clipboard.WriteAll(selected.item.Command); err != nil {
+				return m, m.List.NewStatusMessage(fmt.Sprintf("复制失败: %v", err))
+			}
+			return m, m.List.NewStatusMessage("已复制到剪贴板")
+		}
+	case tea.WindowSizeMsg:
+		m.List.SetWidth(msg.Width)
+	}
+	var cmd tea.Cmd
+	m.List, cmd = m.List.Update(msg)
+	return m, cmd
+}
+
+// remove 从列表中删除选中的历史，之后的历史序号依次减一。
+// list.RemoveItem 在过滤状态下会删错匹配项，因此重建列表后重新过滤
+func (m *HistoryBrowser) remove(deleted historyListItem) {
+	cursor, global := m.List.Index(), m.List.GlobalIndex()
+	items := make([]list.Item, 0, len(m.List.Items()))
+	for i, it := range m.List.Items() {
+		item := it.(historyListItem)
+		if i == global {
+			continue
+		}
+		if item.index > deleted.index {
+			item.index--
+		}
+		items = append(items, item)
+	}
+	filtered, filter := m.List.IsFiltered(), m.List.FilterValue()
+	m.List.SetItems(items)
+	if filtered {
+		m.List.SetFilterText(filter)
+		if len(m.List.VisibleItems()) == 0 {
+			m.List.ResetFilter()
+		}
+	}
+	m.List.Select(max(0, min(cursor, len(m.List.VisibleItems())-1)))
+}
+
+func (m *HistoryBrowser) View() string {
+	return BaseFocusStyle.Render(m.List.View())
+}
+
+// Restore 实现 tui.Model，历史浏览器没有需要恢复的状态
+func (m *HistoryBrowser) Restore(old tui.Model) {}
+
+// GetAction 返回退出时的操作，选中命令时为 select
+func (m *HistoryBrowser) GetAction() string {
+	return m.action
+}
+
+// GetActionPayload 返回选中的命令
+func (m *HistoryBrowser) GetActionPayload() any {
+	return m.payload
+}
+
+// ShowHistoryBrowser 打开历史浏览器浮层，输入框有内容时作为初始过滤条件
+func (m *Prompt) ShowHistoryBrowser() tea.Cmd {
+	if err := m.refreshHistoryItemsFromFile(); err != nil {
+		logger.Warnf("同步历史失败: %v", err)
+	}
+	m.historyMu.Lock()
+	items := append([]HistoryItem{}, m.historyItems...)
+	m.historyMu.Unlock()
+
+	browser := NewHistoryBrowser(items, m.width, m.height)
+	browser.OnDelete = m.DeleteHistoryItem
+	if value := m.Value(); value != "" {
+		browser.List.SetFilterText(value)
+	}
+	return m.OpenOverlay(browser)
+}
+
+// DeleteHistoryItem 从内存与历史后端中删除时间戳与命令都相同的历史项
+func (m *Prompt) DeleteHistoryItem(item HistoryItem) error {
+	if m.historyStore != nil {
+		if err := DeleteHistory(m.historyStore, item); err != nil {
+			return err
+		}
+	}
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	m.historyItems = removeHistoryItem(m.historyItems, item)
+	m.historyIndex = len(m.historyItems)
+	return nil
+}
+
+// removeHistoryItem 删除第一个时间戳与命令都相同的历史项
+func removeHistoryItem(items []HistoryItem, item HistoryItem) []HistoryItem {
+	for i, it := range items {
+		if it.Timestamp == item.Timestamp && it.Command == item.Command {
+			return append(items[:i:i], items[i+1:]...)
+		}
+	}
+	return items
+}
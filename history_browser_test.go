@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHistoryBrowser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	p := NewPrompt(WithHistoryFile(path), WithHistoryOptions(HistoryOptions{}))
+	for i, command := range []string{"a := 1", "b := 2", "c := 3"} {
+		p.AppendHistoryItem(command, time.Unix(int64(i+1), 0), 0)
+	}
+
+	p.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	browser, ok := p.overlay.(*HistoryBrowser)
+	if !ok {
+		t.Fatalf("ctrl+r overlay = %T, want *HistoryBrowser", p.overlay)
+	}
+	if got := browser.List.SelectedItem().(historyListItem).item.Command; got != "c := 3" {
+		t.Errorf("first item = %q, want the latest command", got)
+	}
+
+	// 删除最新的一条，同步删除历史文件中的记录
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	items, err := readHistoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := historyCommands(items), []string{"a := 1", "b := 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history file = %q, want %q", got, want)
+	}
+	if got, want := historyCommands(p.historyItems), []string{"a := 1", "b := 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyItems = %q, want %q", got, want)
+	}
+
+	// 回车选中后关闭浮层，并将命令填入输入框
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if browser.GetAction() != "select" || browser.GetActionPayload() != "b := 2" {
+		t.Errorf("action = %s %v", browser.GetAction(), browser.GetActionPayload())
+	}
+	for _, msg := range cmd().(tea.BatchMsg) {
+		p.Update(msg())
+	}
+	if p.HasOverlay() || p.Value() != "b := 2" {
+		t.Errorf("after select overlay = %v, value = %q", p.HasOverlay(), p.Value())
+	}
+}
+
+// Test: 输入的过滤条件经由 Prompt 转发的过滤结果生效，过滤状态下删除后序号依次前移
+func TestHistoryBrowserFilter(t *testing.T) {
+	p := NewPrompt(WithHistoryStore(NewMemoryHistoryStore()))
+	for i, command := range []string{"a := 1", "b := 2", "c := 3", "bb := 4"} {
+		p.AppendHistoryItem(command, time.Unix(int64(i+1), 0), 0)
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	browser := p.overlay.(*HistoryBrowser)
+	browser.List.FilterInput.Cursor.SetMode(cursor.CursorStatic)
+
+	// 只执行按键返回的过滤命令，其结果与其他异步消息一样经过 Prompt.Update
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, c := range msg {
+				run(c)
+			}
+		case list.FilterMatchesMsg:
+			p.Update(msg)
+		}
+	}
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("/")},
+		{Type: tea.KeyRunes, Runes: []rune("b")},
+		{Type: tea.KeyEnter},
+	} {
+		_, cmd := p.Update(msg)
+		run(cmd)
+	}
+	titles := func() []string {
+		var got []string
+		for _, item := range browser.List.VisibleItems() {
+			got = append(got, item.(historyListItem).Title())
+		}
+		return got
+	}
+	if got, want := titles(), []string{"1  b := 2", "3  bb := 4"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("filtered items = %q, want %q", got, want)
+	}
+
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if got, want := titles(), []string{"2  bb := 4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after delete = %q, want %q", got, want)
+	}
+	if got, want := historyCommands(p.historyItems), []string{"a := 1", "c := 3", "bb := 4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyItems = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package prompt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+const (
+	// HistoryKeyEnv 历史加密密钥的环境变量，值为 base64 或十六进制编码的 32 字节密钥
+	HistoryKeyEnv = "CODE_PROMPT_HISTORY_KEY"
+	// encryptedHistoryPrefix 加密历史行的前缀，便于识别格式与后续升级算法
+	encryptedHistoryPrefix = "enc1:"
+	// historyKeySize AES-256 的密钥长度
+	historyKeySize = 32
+)
+
+// encryptedHistoryAAD 每行的附加认证数据，防止加密行被挪作他用
+var encryptedHistoryAAD = []byte("code-prompt history v1")
+
+// errHistoryDecrypt 密钥错误或内容被篡改
+var errHistoryDecrypt = errors.New("历史记录解密失败，密钥错误或内容被篡改")
+
+// GenerateHistoryKey 生成 base64 编码的随机密钥，可以写入密钥文件或 HistoryKeyEnv
+func GenerateHistoryKey() (string, error) {
+	key := make([]byte, historyKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadHistoryKey 读取历史加密密钥，优先使用环境变量 HistoryKeyEnv，其次读取 keyFile。
+// 都没有配置时返回 nil。密钥文件不能被其他用户读取。
+func LoadHistoryKey(keyFile string) ([]byte, error) {
+	if value := strings.TrimSpace(os.Getenv(HistoryKeyEnv)); value != "" {
+		return decodeHistoryKey(value)
+	}
+	if keyFile == "" {
+		return nil, nil
+	}
+	path, err := resolveHistoryFilePath(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("密钥文件权限过于宽松，需要 0600: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHistoryKey(strings.TrimSpace(string(data)))
+}
+
+func decodeHistoryKey(value string) ([]byte, error) {
+	for _, decode := range []func(string) ([]byte, error){base64.StdEncoding.DecodeString, hex.DecodeString} {
+		if key, err := decode(value); err == nil && len(key) == historyKeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("历史加密密钥需要是 base64 或十六进制编码的 %d 字节", historyKeySize)
+}
+
+// NewEncryptedHistoryStore 创建 AES-GCM 加密的历史后端。每条历史单独加密为一行，
+// 内容为 JSONL 格式的记录，因此仍然是逐行追加，文件锁与增量加载的行为与明文后端一致。
+// 无法解密的行在加载时跳过，重写时原样保留；没有任何一行能解密时认为密钥错误，返回错误。
+func NewEncryptedHistoryStore(path string, key []byte) (*FileHistoryStore, error) {
+	aead, err := newHistoryAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return newFileHistoryStore(path, encryptedHistoryCodec(aead))
+}
+
+func newHistoryAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != historyKeySize {
+		return nil, fmt.Errorf("历史加密密钥长度需要为 %d 字节", historyKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptedHistoryCodec(aead cipher.AEAD) historyCodec {
+	return historyCodec{
+		decode: func(line string) (HistoryItem, error) {
+			return decryptHistoryLine(line, aead)
+		},
+		format: func(item HistoryItem) string {
+			return formatEncryptedHistoryItem(item, aead)
+		},
+	}
+}
+
+func formatEncryptedHistoryItem(item HistoryItem, aead cipher.AEAD) string {
+	plaintext, err := json.Marshal(item)
+	if err != nil {
+		logger.Warnf("编码历史记录失败: %v", err)
+		return ""
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		logger.Warnf("生成随机数失败: %v", err)
+		return ""
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, encryptedHistoryAAD)
+	return encryptedHistoryPrefix + base64.StdEncoding.EncodeToString(sealed) + "\n"
+}
+
+func decryptHistoryLine(line string, aead cipher.AEAD) (HistoryItem, error) {
+	var item HistoryItem
+	if !strings.HasPrefix(line, encryptedHistoryPrefix) {
+		return item, fmt.Errorf("历史记录未加密，请先迁移: %.20s", line)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, encryptedHistoryPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return item, errHistoryDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, encryptedHistoryAAD)
+	if err != nil {
+		return item, errHistoryDecrypt
+	}
+	if err := json.Unmarshal(plaintext, &item); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// EncryptHistoryFile 将明文历史文件原地迁移为加密格式，返回迁移的条数。
+// 明文格式自动识别（见 ParseHistory），迁移在文件锁内完成，其他进程需要改用加密后端。
+func EncryptHistoryFile(path string, key []byte) (int, error) {
+	store, err := NewEncryptedHistoryStore(path, key)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = store.update(func(file *os.File, _ func() (int, error)) ([]byte, bool, error) {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, false, err
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(data)), encryptedHistoryPrefix) {
+			return nil, false, fmt.Errorf("历史文件已经加密: %s", store.Path())
+		}
+		// 默认历史文件的多行命令是转义保存的，不能按 zsh 的续行解析
+		var items []HistoryItem
+		if format := DetectHistoryFormat(data); format == HistoryFormatZsh {
+			items, err = parseHistoryItems(bytes.NewReader(data))
+		} else {
+			items, _, err = ParseHistory(data, format)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		count = len(items)
+		return store.overwrite(file, items, nil)
+	})
+	return count, err
+}
@@ -0,0 +1,102 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedHistoryStore(t *testing.T) {
+	encoded, err := GenerateHistoryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(HistoryKeyEnv, encoded)
+	key, err := LoadHistoryKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte(": 1:0;token := \"secret\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := EncryptHistoryFile(path, key); err != nil || n != 1 {
+		t.Fatalf("EncryptHistoryFile() = %d, %v", n, err)
+	}
+	if _, err := EncryptHistoryFile(path, key); err == nil {
+		t.Error("EncryptHistoryFile() on encrypted file should fail")
+	}
+
+	store, err := NewEncryptedHistoryStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := HistoryItem{Timestamp: 2, Command: "b := 2", Meta: map[string]string{"k": "v"}}
+	if err := store.Append(item, HistoryOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Count(string(data), encryptedHistoryPrefix) != 2 {
+		t.Errorf("history file is not encrypted per line:\n%s", data)
+	}
+	items, err := store.Search(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HistoryItem{{Timestamp: 1, Command: `token := "secret"`}, item}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Search(nil) = %+v, want %+v", items, want)
+	}
+
+	// 损坏的行被跳过，重写时原样保留
+	appendHistoryLines(t, path, encryptedHistoryPrefix+"broken\n: 3:0;plain\n")
+	if items, err := store.Search(nil); err != nil || len(items) != 2 {
+		t.Errorf("Search() with broken lines = %+v, %v", items, err)
+	}
+	if err := TrimHistory(store, 1); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 ||
+		lines[0] != encryptedHistoryPrefix+"broken" || lines[1] != ": 3:0;plain" {
+		t.Errorf("history file after trim:\n%s", data)
+	}
+	if items, _ := store.Search(nil); !reflect.DeepEqual(items, []HistoryItem{item}) {
+		t.Errorf("Search() after trim = %+v", items)
+	}
+
+	// 错误的密钥无法读取，也不会重写文件
+	other, _ := GenerateHistoryKey()
+	otherKey, _ := decodeHistoryKey(other)
+	wrong, _ := NewEncryptedHistoryStore(path, otherKey)
+	if _, err := wrong.Search(nil); err == nil {
+		t.Error("Search() with wrong key should fail")
+	}
+	if err := TrimHistory(wrong, 1); err == nil {
+		t.Error("TrimHistory() with wrong key should fail")
+	}
+}
+
+func TestLoadHistoryKeyFile(t *testing.T) {
+	t.Setenv(HistoryKeyEnv, "")
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte(strings.Repeat("ab", historyKeySize)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadHistoryKey(path)
+	if err != nil || len(key) != historyKeySize {
+		t.Errorf("LoadHistoryKey() = %x, %v", key, err)
+	}
+	if key, err := LoadHistoryKey(""); key != nil || err != nil {
+		t.Errorf("LoadHistoryKey(\"\") = %x, %v, want nil", key, err)
+	}
+}
@@ -0,0 +1,308 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 历史展开参考 zsh 的语法，按回车时展开并回填到输入框，再次回车才会执行。
+//
+// 事件：
+//
+//	!!        上一条命令
+//	!n        第 n 条命令，序号与 /history 的输出一致（从 0 开始）
+//	!-n       倒数第 n 条命令，!-1 等同于 !!
+//	!prefix   最近一条以 prefix 开头的命令
+//	!?str?    最近一条包含 str 的命令，位于行尾时结尾的 ? 可以省略
+//
+// 单词（命令按空白分词，引号内的内容视为一个单词，第 0 个单词为命令开头）：
+//
+//	!$  !^  !*     上一条命令的最后一个、第一个、全部参数（第 1 个单词到最后）
+//	事件:n        事件的第 n 个单词，如 !!:2、!-2:0
+//	事件:n-m      第 n 到 m 个单词
+//	事件:^ :$ :*  同上
+//
+// 快速替换：
+//
+//	^old^new[^]   将上一条命令中第一次出现的 old 替换为 new，只在输入开头且有第二个 ^ 时生效
+//
+// 转义与限制：
+//   - `\!` 输入字面的 !（反斜杠会被移除），输入开头的 `\^` 禁用快速替换
+//   - 单引号、双引号与反引号内的内容不展开，保证 Go 的字符串与字符字面量不受影响
+//   - ! 之后是空白、=、( 或位于行尾时不展开，因此 != 与 !(x) 保持原样
+//   - 为避免与 Go 的取反运算（如 !ok、!!ok、!*p）冲突，!!、!*、!prefix 与 !?str? 只在输入开头
+//     且整个单词都是历史引用时展开，!prefix 找不到命令时按 Go 代码保持原样
+//
+// 找不到事件或单词时返回 HistoryExpansionError，输入不会被执行。
+
+// HistoryExpansionError 历史展开失败
+type HistoryExpansionError struct {
+	Reason string
+	Event  string
+}
+
+func (e *HistoryExpansionError) Error() string {
+	if e.Event == "" {
+		return "wgo: " + e.Reason
+	}
+	return fmt.Sprintf("wgo: %s: %s", e.Reason, e.Event)
+}
+
+func noSuchEvent(event string) error {
+	return &HistoryExpansionError{Reason: "no such event", Event: event}
+}
+
+// ExpandHistory 展开 input 中的历史引用，history 按时间顺序排列。
+// changed 表示是否发生了展开；只有转义被移除时 changed 为 false，但仍返回移除转义后的输入。
+func ExpandHistory(input string, history []string) (expanded string, changed bool, err error) {
+	if strings.HasPrefix(input, `\^`) {
+		return input[1:], false, nil
+	}
+	if strings.HasPrefix(input, "^") && strings.Contains(input[1:], "^") {
+		return quickSubstitute(input, history)
+	}
+
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(input); {
+		ch := input[i]
+		if quote != 0 {
+			b.WriteByte(ch)
+			if ch == '\\' && quote != '`' && i+1 < len(input) {
+				b.WriteByte(input[i+1])
+				i += 2
+				continue
+			}
+			if ch == quote {
+				quote = 0
+			}
+			i++
+			continue
+		}
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			b.WriteByte(ch)
+			i++
+		case ch == '\\' && i+1 < len(input) && input[i+1] == '!':
+			b.WriteByte('!')
+			i += 2
+		case ch == '!':
+			text, consumed, err := expandEvent(input, i, history)
+			if err != nil {
+				return input, false, err
+			}
+			if consumed == 0 {
+				b.WriteByte(ch)
+				i++
+				continue
+			}
+			b.WriteString(text)
+			i += consumed
+			changed = true
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String(), changed, nil
+}
+
+// goLikeHistoryRefRe 与 Go 代码写法相同的历史引用需要匹配的完整单词：!!、!* 与 !prefix，可带单词选择器
+var goLikeHistoryRefRe = regexp.MustCompile(`^!(\*|(!|[^\s:!*][^\s:]*)(:(\^|\$|\*|\d+(-\d+)?))?)$`)
+
+// expandEvent 展开 input[i] 处以 ! 开头的引用，返回展开结果与消耗的字节数，0 表示不展开
+func expandEvent(input string, i int, history []string) (string, int, error) {
+	rest := input[i+1:]
+	if rest == "" || strings.ContainsRune(" \t\n=(", rune(rest[0])) {
+		return "", 0, nil
+	}
+	atStart := strings.TrimSpace(input[:i]) == ""
+	if c := rest[0]; c == '!' || c == '*' || isIdentStart(c) {
+		token := input[i:]
+		if end := strings.IndexAny(token, " \t\n"); end >= 0 {
+			token = token[:end]
+		}
+		if !atStart || !goLikeHistoryRefRe.MatchString(token) {
+			return "", 0, nil
+		}
+	}
+	last := func() (string, error) {
+		if len(history) == 0 {
+			return "", noSuchEvent("!")
+		}
+		return history[len(history)-1], nil
+	}
+
+	var (
+		event    string
+		consumed = 1
+		err      error
+	)
+	switch c := rest[0]; {
+	case c == '!':
+		event, err = last()
+		consumed++
+	case c == '$' || c == '^' || c == '*':
+		// !$ 等同于 !!:$
+		if event, err = last(); err != nil {
+			return "", 0, err
+		}
+		words, err := selectWords(event, string(c))
+		return words, consumed + 1, err
+	case c == ':':
+		event, err = last()
+	case c == '-' && len(rest) > 1 && isDigit(rest[1]):
+		digits := leadingDigits(rest[1:])
+		n, _ := strconv.Atoi(digits)
+		consumed += 1 + len(digits)
+		if n < 1 || n > len(history) {
+			return "", 0, noSuchEvent("-" + digits)
+		}
+		event = history[len(history)-n]
+	case isDigit(c):
+		digits := leadingDigits(rest)
+		n, _ := strconv.Atoi(digits)
+		consumed += len(digits)
+		if n >= len(history) {
+			return "", 0, noSuchEvent(digits)
+		}
+		event = history[n]
+	case c == '?' && atStart:
+		search := rest[1:]
+		if end := strings.IndexByte(search, '?'); end >= 0 {
+			search = search[:end]
+			consumed += len(search) + 2
+		} else {
+			consumed += len(search) + 1
+		}
+		event, err = findHistory(history, "?"+search, func(cmd string) bool { return strings.Contains(cmd, search) })
+	case atStart:
+		prefix := rest
+		if end := strings.IndexAny(prefix, " \t\n:"); end >= 0 {
+			prefix = prefix[:end]
+		}
+		consumed += len(prefix)
+		if event, err = findHistory(history, prefix, func(cmd string) bool { return strings.HasPrefix(cmd, prefix) }); err != nil {
+			// 没有匹配的命令时按 Go 代码处理，如 !ok
+			return "", 0, nil
+		}
+	default:
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	// 单词选择 :n、:n-m、:^、:$、:*
+	designator := input[i+consumed:]
+	if !strings.HasPrefix(designator, ":") || len(designator) < 2 {
+		return event, consumed, nil
+	}
+	spec := designator[1:]
+	switch spec[0] {
+	case '^', '$', '*':
+		spec = spec[:1]
+	default:
+		if !isDigit(spec[0]) {
+			return event, consumed, nil
+		}
+		digits := leadingDigits(spec)
+		if len(spec) > len(digits)+1 && spec[len(digits)] == '-' && isDigit(spec[len(digits)+1]) {
+			digits += "-" + leadingDigits(spec[len(digits)+1:])
+		}
+		spec = digits
+	}
+	words, err := selectWords(event, spec)
+	return words, consumed + 1 + len(spec), err
+}
+
+func findHistory(history []string, event string, match func(cmd string) bool) (string, error) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if match(history[i]) {
+			return history[i], nil
+		}
+	}
+	return "", noSuchEvent(event)
+}
+
+// selectWords 按单词选择器从命令中取出单词
+func selectWords(command, spec string) (string, error) {
+	words := historyWords(command)
+	badWord := &HistoryExpansionError{Reason: "bad word selector", Event: spec}
+	from, to := 0, 0
+	switch spec {
+	case "^":
+		from, to = 1, 1
+	case "$":
+		from, to = len(words)-1, len(words)-1
+	case "*":
+		if len(words) < 2 {
+			return "", nil
+		}
+		from, to = 1, len(words)-1
+	default:
+		start, end, isRange := strings.Cut(spec, "-")
+		from, _ = strconv.Atoi(start)
+		to = from
+		if isRange {
+			to, _ = strconv.Atoi(end)
+		}
+	}
+	if from < 0 || to >= len(words) || from > to {
+		return "", badWord
+	}
+	return strings.Join(words[from:to+1], " "), nil
+}
+
+// historyWords 按空白分词，保留引号等原始内容，引号未闭合时退回按空白切分
+func historyWords(command string) []string {
+	tokens, err := tokenizeCommandLine(command)
+	if err != nil {
+		return strings.Fields(command)
+	}
+	words := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		words = append(words, command[tok.Start:tok.End])
+	}
+	return words
+}
+
+// quickSubstitute 处理 ^old^new[^]
+func quickSubstitute(input string, history []string) (string, bool, error) {
+	parts := strings.SplitN(input[1:], "^", 3)
+	if len(parts) < 2 || parts[0] == "" {
+		return input, false, &HistoryExpansionError{Reason: "bad substitution", Event: input}
+	}
+	if len(history) == 0 {
+		return input, false, noSuchEvent("^")
+	}
+	last := history[len(history)-1]
+	if !strings.Contains(last, parts[0]) {
+		return input, false, &HistoryExpansionError{Reason: "substitution failed"}
+	}
+	expanded := strings.Replace(last, parts[0], parts[1], 1)
+	if len(parts) == 3 {
+		expanded += parts[2]
+	}
+	return expanded, true, nil
+}
+
+// isIdentStart 是否可以作为 Go 标识符的开头，非 ASCII 字符按字母处理
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func leadingDigits(s string) string {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return s[:n]
+}
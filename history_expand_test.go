@@ -0,0 +1,88 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandHistory(t *testing.T) {
+	history := []string{
+		"fmt.Println(1)",
+		`x := strings.Split("a b", " ")`,
+		"go build ./cmd/tool ./pkg",
+		"ls -la /tmp",
+	}
+	tests := []struct {
+		input   string
+		want    string
+		changed bool
+		err     string
+	}{
+		{"!!", "ls -la /tmp", true, ""},
+		{"!0", "fmt.Println(1)", true, ""},
+		{"!-2", "go build ./cmd/tool ./pkg", true, ""},
+		{"!fmt", "fmt.Println(1)", true, ""},
+		{"!?build?", "go build ./cmd/tool ./pkg", true, ""},
+		{"!?Split", `x := strings.Split("a b", " ")`, true, ""},
+		{"echo !$", "echo /tmp", true, ""},
+		{"echo !^", "echo -la", true, ""},
+		{"!*", "-la /tmp", true, ""},
+		{"!-2:2", "./cmd/tool", true, ""},
+		{"!-2:1-2", "build ./cmd/tool", true, ""},
+		{"!1:$", `" ")`, true, ""},
+		{"!!:0 -l", "ls -l", true, ""},
+		{"^la^l", "ls -l /tmp", true, ""},
+		{"^tmp^var^/log", "ls -la /var/log", true, ""},
+		// 不展开的情况
+		{`\!ok`, "!ok", false, ""},
+		{"a != b", "a != b", false, ""},
+		{"x := !ok && !done", "x := !ok && !done", false, ""},
+		{"!(a)", "!(a)", false, ""},
+		{`s := "!!"`, `s := "!!"`, false, ""},
+		{"r := '!'", "r := '!'", false, ""},
+		{"echo \\!!", "echo !!", false, ""},
+		{`\^a^b`, "^a^b", false, ""},
+		{"!*p", "!*p", false, ""},
+		{"if !*verbose {", "if !*verbose {", false, ""},
+		{"!!b", "!!b", false, ""},
+		{"x := !!ok", "x := !!ok", false, ""},
+		{"echo !*", "echo !*", false, ""},
+		{"!ok", "!ok", false, ""},
+		{"!nothing", "!nothing", false, ""},
+		{"^x", "^x", false, ""},
+		{"^uint(0)", "^uint(0)", false, ""},
+		// 错误
+		{"!9", "", false, "wgo: no such event: 9"},
+		{"!-9", "", false, "wgo: no such event: -9"},
+		{"!!:9", "", false, "wgo: bad word selector: 9"},
+		{"^xyz^a", "", false, "wgo: substitution failed"},
+	}
+	for _, tt := range tests {
+		got, changed, err := ExpandHistory(tt.input, history)
+		if tt.err != "" {
+			var expandErr *HistoryExpansionError
+			if err == nil || err.Error() != tt.err || !errors.As(err, &expandErr) {
+				t.Errorf("ExpandHistory(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpandHistory(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("ExpandHistory(%q) = %q, %v, want %q, %v", tt.input, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestExpandHistoryEmpty(t *testing.T) {
+	if _, _, err := ExpandHistory("!!", nil); err == nil {
+		t.Errorf("ExpandHistory(!!) with empty history should fail")
+	}
+	for _, input := range []string{"if !*verbose {", "!!ok", "^x"} {
+		if got, changed, err := ExpandHistory(input, nil); got != input || changed || err != nil {
+			t.Errorf("ExpandHistory(%q) with empty history = %q, %v, %v", input, got, changed, err)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HistoryFormat 导入导出历史时使用的文件格式
+type HistoryFormat string
+
+const (
+	// HistoryFormatBash bash 的默认格式，每行一条命令，没有时间戳，无法区分多行命令
+	HistoryFormatBash HistoryFormat = "bash"
+	// HistoryFormatBashTimestamp 设置了 HISTTIMEFORMAT 的 bash 格式，每条命令前有一行 #时间戳
+	HistoryFormatBashTimestamp HistoryFormat = "bash-timestamp"
+	// HistoryFormatZsh zsh 的扩展格式（EXTENDED_HISTORY），: 时间戳:耗时;命令，多行命令以反斜杠续行。
+	// 默认历史文件的行格式相同，但多行命令转义为一行保存
+	HistoryFormatZsh HistoryFormat = "zsh"
+	// HistoryFormatFish fish 的 YAML 格式，- cmd: 命令 与 when: 时间戳
+	HistoryFormatFish HistoryFormat = "fish"
+	// HistoryFormatJSONL JSON Lines 格式，见 NewJSONLHistoryStore
+	HistoryFormatJSONL HistoryFormat = "jsonl"
+)
+
+// HistoryFormats 返回支持的历史格式
+func HistoryFormats() []HistoryFormat {
+	return []HistoryFormat{HistoryFormatZsh, HistoryFormatBash, HistoryFormatBashTimestamp, HistoryFormatFish, HistoryFormatJSONL}
+}
+
+func isHistoryFormat(format HistoryFormat) bool {
+	for _, f := range HistoryFormats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	zshHistoryLineRe    = regexp.MustCompile(`^: *\d+:\d+;`)
+	bashTimestampLineRe = regexp.MustCompile(`^#\d+\s*$`)
+)
+
+// historyDetectLines 识别格式时最多检查的非空行数
+const historyDetectLines = 50
+
+// DetectHistoryFormat 根据内容识别历史格式，无法识别时按纯 bash 格式处理
+func DetectHistoryFormat(data []byte) HistoryFormat {
+	checked := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			return HistoryFormatFish
+		case zshHistoryLineRe.MatchString(line):
+			return HistoryFormatZsh
+		case strings.HasPrefix(line, "{") && json.Valid([]byte(line)):
+			return HistoryFormatJSONL
+		case bashTimestampLineRe.MatchString(line):
+			return HistoryFormatBashTimestamp
+		}
+		checked++
+		if checked >= historyDetectLines {
+			break
+		}
+	}
+	return HistoryFormatBash
+}
+
+// ParseHistory 按格式解析历史，format 为空时自动识别，返回实际使用的格式。
+// 无法解析的行会被跳过。
+func ParseHistory(data []byte, format HistoryFormat) ([]HistoryItem, HistoryFormat, error) {
+	if format == "" {
+		format = DetectHistoryFormat(data)
+	}
+	var items []HistoryItem
+	switch format {
+	case HistoryFormatBash:
+		items = parseBashHistory(data)
+	case HistoryFormatBashTimestamp:
+		items = parseBashTimestampHistory(data)
+	case HistoryFormatZsh:
+		items = parseZshHistory(data)
+	case HistoryFormatFish:
+		items = parseFishHistory(data)
+	case HistoryFormatJSONL:
+		parsed, err := parseJSONLHistoryItems(bytes.NewReader(data))
+		if err != nil {
+			return nil, format, err
+		}
+		items = parsed
+	default:
+		return nil, format, fmt.Errorf("不支持的历史格式: %s", format)
+	}
+	return items, format, nil
+}
+
+// WriteHistory 按格式写入历史，format 为空时使用 zsh 扩展格式。
+// 纯 bash 格式没有时间戳，多行命令会被拆成多条。
+func WriteHistory(w io.Writer, items []HistoryItem, format HistoryFormat) error {
+	if format != "" && !isHistoryFormat(format) {
+		return fmt.Errorf("不支持的历史格式: %s", format)
+	}
+	var b strings.Builder
+	for _, item := range items {
+		switch format {
+		case "", HistoryFormatZsh:
+			fmt.Fprintf(&b, ": %d:%d;%s\n", item.Timestamp, item.DurationSeconds, strings.ReplaceAll(item.Command, "\n", "\\\n"))
+		case HistoryFormatBash:
+			b.WriteString(item.Command + "\n")
+		case HistoryFormatBashTimestamp:
+			fmt.Fprintf(&b, "#%d\n%s\n", item.Timestamp, item.Command)
+		case HistoryFormatFish:
+			fmt.Fprintf(&b, "- cmd: %s\n  when: %d\n", escapeHistoryCommand(item.Command), item.Timestamp)
+		case HistoryFormatJSONL:
+			b.WriteString(formatJSONLHistoryItem(item))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func historyLines(data []byte) []string {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return lines
+}
+
+func parseBashHistory(data []byte) []HistoryItem {
+	items := make([]HistoryItem, 0)
+	for _, line := range historyLines(data) {
+		if strings.TrimSpace(line) != "" {
+			items = append(items, HistoryItem{Command: line})
+		}
+	}
+	return items
+}
+
+// parseBashTimestampHistory 解析带 #时间戳 的 bash 历史，时间戳之间的多行属于同一条命令
+func parseBashTimestampHistory(data []byte) []HistoryItem {
+	items := make([]HistoryItem, 0)
+	var (
+		current *HistoryItem
+		lines   []string
+	)
+	flush := func() {
+		if current != nil && len(lines) > 0 {
+			current.Command = strings.Join(lines, "\n")
+			items = append(items, *current)
+		}
+		current, lines = nil, nil
+	}
+	for _, line := range historyLines(data) {
+		if bashTimestampLineRe.MatchString(line) {
+			flush()
+			ts, _ := strconv.ParseInt(strings.TrimSpace(line[1:]), 10, 64)
+			current = &HistoryItem{Timestamp: ts}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if current == nil {
+			// 没有时间戳的命令
+			current = &HistoryItem{}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return items
+}
+
+// parseZshHistory 解析 zsh 扩展格式，支持 zsh 以反斜杠续行保存的多行命令与元字符编码，
+// zsh 不转义命令中的反斜杠，命令保持原样
+func parseZshHistory(data []byte) []HistoryItem {
+	items := make([]HistoryItem, 0)
+	var pending string
+	for _, line := range historyLines(unmetafyZsh(data)) {
+		if pending != "" {
+			pending += "\n" + line
+		} else {
+			pending = line
+		}
+		if trailingBackslashes(line)%2 == 1 {
+			pending = pending[:len(pending)-1]
+			continue
+		}
+		if strings.TrimSpace(pending) != "" {
+			item, err := splitHistoryLine(strings.TrimLeft(pending, " \t"))
+			if err != nil {
+				logger.Warnf("解析历史行失败: %v", err)
+			} else {
+				items = append(items, item)
+			}
+		}
+		pending = ""
+	}
+	return items
+}
+
+// unmetafyZsh 还原 zsh 历史文件中的元字符：0x83 之后的字节与 32 异或
+func unmetafyZsh(data []byte) []byte {
+	const meta = 0x83
+	if bytes.IndexByte(data, meta) < 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == meta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^32)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+func trailingBackslashes(s string) int {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == '\\' {
+		n++
+	}
+	return n
+}
+
+// parseFishHistory 解析 fish 的历史文件，只读取 cmd 与 when 字段
+func parseFishHistory(data []byte) []HistoryItem {
+	items := make([]HistoryItem, 0)
+	for _, line := range historyLines(data) {
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			items = append(items, HistoryItem{Command: unescapeHistoryCommand(strings.TrimPrefix(line, "- cmd: "))})
+		case strings.HasPrefix(line, "  when: ") && len(items) > 0:
+			ts, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "  when: ")), 10, 64)
+			if err == nil {
+				items[len(items)-1].Timestamp = ts
+			}
+		}
+	}
+	return items
+}
+
+// mergeHistoryItems 将导入的历史合并到已有历史中并按时间排序，返回合并结果与新增条数。
+// 时间戳与命令都相同的历史视为重复；没有时间戳的历史只要命令已存在就视为重复。
+func mergeHistoryItems(existing, imported []HistoryItem) ([]HistoryItem, int) {
+	type key struct {
+		ts      int64
+		command string
+	}
+	seen := make(map[key]bool, len(existing))
+	commands := make(map[string]bool, len(existing))
+	merged := append([]HistoryItem{}, existing...)
+	for _, item := range existing {
+		seen[key{item.Timestamp, item.Command}] = true
+		commands[item.Command] = true
+	}
+	added := 0
+	for _, item := range imported {
+		k := key{item.Timestamp, item.Command}
+		if seen[k] || (item.Timestamp == 0 && commands[item.Command]) {
+			continue
+		}
+		seen[k] = true
+		commands[item.Command] = true
+		merged = append(merged, item)
+		added++
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp < merged[j].Timestamp
+	})
+	return merged, added
+}
+
+// ImportHistory 从文件导入历史并与当前历史合并去重，format 为空时自动识别，返回新增的条数。
+// 导入的命令与执行的命令一样按 HistoryOptions 忽略并脱敏
+func (m *Prompt) ImportHistory(path string, format HistoryFormat) (int, error) {
+	resolved, err := resolveHistoryFilePath(path)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return 0, err
+	}
+	parsed, _, err := ParseHistory(data, format)
+	if err != nil {
+		return 0, err
+	}
+	imported := parsed[:0]
+	for _, item := range parsed {
+		var ok bool
+		if item.Command, ok = m.historyCommand(item.Command); ok {
+			imported = append(imported, item)
+		}
+	}
+
+	// 有历史后端时在写锁内合并，避免覆盖其他写入方同时追加的历史
+	var (
+		merged []HistoryItem
+		added  int
+	)
+	if m.historyStore != nil {
+		err = m.historyStore.Update(func(items []HistoryItem) ([]HistoryItem, bool) {
+			merged, added = mergeHistoryItems(items, imported)
+			return trimHistoryItems(merged, m.historyOptions.MaxFileEntries), added > 0
+		})
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		m.historyMu.Lock()
+		merged, added = mergeHistoryItems(m.historyItems, imported)
+		m.historyMu.Unlock()
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	m.historyMu.Lock()
+	m.historyItems = trimHistoryItems(merged, m.historyOptions.MaxEntries)
+	m.historyIndex = len(m.historyItems)
+	m.historyMu.Unlock()
+	return added, nil
+}
+
+// ExportHistory 将全部历史导出到文件，format 为空时使用 zsh 扩展格式，返回导出的条数
+func (m *Prompt) ExportHistory(path string, format HistoryFormat) (int, error) {
+	resolved, err := resolveHistoryFilePath(path)
+	if err != nil {
+		return 0, err
+	}
+	items, err := m.historySnapshot()
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(resolved, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	if err := WriteHistory(file, items, format); err != nil {
+		file.Close()
+		return 0, err
+	}
+	return len(items), file.Close()
+}
+
+// historySnapshot 返回全部历史，设置了历史后端时以后端为准
+func (m *Prompt) historySnapshot() ([]HistoryItem, error) {
+	if m.historyStore != nil {
+		return m.historyStore.Search(nil)
+	}
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	return append([]HistoryItem{}, m.historyItems...), nil
+}
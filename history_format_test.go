@@ -0,0 +1,138 @@
+package prompt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHistoryFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format HistoryFormat
+		want   []HistoryItem
+	}{
+		{
+			name:   "bash",
+			data:   "ls -la\ncd /tmp\n\n",
+			format: HistoryFormatBash,
+			want:   []HistoryItem{{Command: "ls -la"}, {Command: "cd /tmp"}},
+		},
+		{
+			name:   "bash-timestamp",
+			data:   "#1700000000\nls -la\n#1700000005\nfor i in 1 2; do\necho $i\ndone\n",
+			format: HistoryFormatBashTimestamp,
+			want: []HistoryItem{
+				{Timestamp: 1700000000, Command: "ls -la"},
+				{Timestamp: 1700000005, Command: "for i in 1 2; do\necho $i\ndone"},
+			},
+		},
+		{
+			name:   "zsh",
+			data:   ": 1700000000:3;make test\n: 1700000010:0;echo a\\\necho b\n: 1700000020:0;printf 'a\\nb' C:\\\\tmp\n",
+			format: HistoryFormatZsh,
+			want: []HistoryItem{
+				{Timestamp: 1700000000, DurationSeconds: 3, Command: "make test"},
+				{Timestamp: 1700000010, Command: "echo a\necho b"},
+				{Timestamp: 1700000020, Command: `printf 'a\nb' C:\\tmp`},
+			},
+		},
+		{
+			name:   "fish",
+			data:   "- cmd: git status\n  when: 1700000000\n  paths:\n    - foo\n- cmd: echo a\\nb\n  when: 1700000001\n",
+			format: HistoryFormatFish,
+			want: []HistoryItem{
+				{Timestamp: 1700000000, Command: "git status"},
+				{Timestamp: 1700000001, Command: "echo a\nb"},
+			},
+		},
+		{
+			name:   "jsonl",
+			data:   `{"timestamp":1,"duration":0,"command":"x","meta":{"cwd":"/tmp"}}` + "\n",
+			format: HistoryFormatJSONL,
+			want:   []HistoryItem{{Timestamp: 1, Command: "x", Meta: map[string]string{"cwd": "/tmp"}}},
+		},
+	}
+	for _, tt := range tests {
+		if got := DetectHistoryFormat([]byte(tt.data)); got != tt.format {
+			t.Errorf("%s: DetectHistoryFormat() = %s", tt.name, got)
+		}
+		items, format, err := ParseHistory([]byte(tt.data), "")
+		if err != nil || format != tt.format || !reflect.DeepEqual(items, tt.want) {
+			t.Errorf("%s: ParseHistory() = %+v, %s, %v, want %+v", tt.name, items, format, err, tt.want)
+		}
+
+		// 导出后重新解析应保持一致（纯 bash 格式没有时间戳）
+		var buf bytes.Buffer
+		if err := WriteHistory(&buf, tt.want, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		again, _, err := ParseHistory(buf.Bytes(), tt.format)
+		if err != nil || !reflect.DeepEqual(again, tt.want) {
+			t.Errorf("%s: round trip = %+v, %v", tt.name, again, err)
+		}
+	}
+}
+
+func TestImportExportHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemoryHistoryStore(
+		HistoryItem{Timestamp: 10, Command: "a"},
+		HistoryItem{Timestamp: 30, Command: "c"},
+	)
+	p := NewPrompt(WithHistoryStore(store))
+	// 其他写入方在导入前追加的历史不会被覆盖
+	if err := store.Append(HistoryItem{Timestamp: 40, Command: "d"}, HistoryOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	source := filepath.Join(dir, "bash_history")
+	if err := os.WriteFile(source, []byte("#10\na\n#20\nb\n#20\nb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd, _ := p.Commands().Lookup("/history")
+	if out, _ := cmd.Exec(p, "/history import "+source); out != "已导入 1 条历史" {
+		t.Errorf("/history import = %q", out)
+	}
+	if got, want := historyCommands(p.historyItems), []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyItems = %q, want %q", got, want)
+	}
+	if got, _ := store.Search(nil); !reflect.DeepEqual(historyCommands(got), []string{"a", "b", "c", "d"}) {
+		t.Errorf("store = %q", historyCommands(got))
+	}
+
+	target := filepath.Join(dir, "fish_history")
+	if out, _ := cmd.Exec(p, "/history export -f fish "+target); !strings.HasPrefix(out, "已导出 4 条历史") {
+		t.Errorf("/history export = %q", out)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- cmd: a\n  when: 10\n- cmd: b\n  when: 20\n- cmd: c\n  when: 30\n- cmd: d\n  when: 40\n"; string(data) != want {
+		t.Errorf("exported = %q, want %q", data, want)
+	}
+}
+
+func TestImportHistoryFiltersAndRedacts(t *testing.T) {
+	p := NewPrompt(WithHistoryOptions(HistoryOptions{IgnoreSpace: true, IgnoreBuiltins: true}))
+	source := filepath.Join(t.TempDir(), "history")
+	data := " secret\n/help\npassword := \"hunter2\"\n"
+	if err := os.WriteFile(source, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	added, err := p.ImportHistory(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || len(p.historyItems) != 1 {
+		t.Fatalf("ImportHistory added %d, items %q", added, historyCommands(p.historyItems))
+	}
+	if cmd := p.historyItems[0].Command; strings.Contains(cmd, "hunter2") {
+		t.Errorf("imported command not redacted: %q", cmd)
+	}
+}
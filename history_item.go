@@ -1,9 +1,9 @@
 package prompt
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -19,28 +19,26 @@ func readHistoryFile(path string) ([]HistoryItem, error) {
 		return nil, err
 	}
 	defer file.Close()
+	return parseHistoryItems(file)
+}
 
-	scanner := bufio.NewScanner(file)
-	items := make([]HistoryItem, 0)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-		item, err := parseHistoryLine(line)
-		if err != nil {
-			logger.Warnf("解析历史行失败: %v", err)
-			continue
-		}
-		items = append(items, item)
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// parseHistoryItems 逐行解析历史记录，跳过无法解析的行
+func parseHistoryItems(r io.Reader) ([]HistoryItem, error) {
+	return zshHistoryCodec.parse(r)
 }
 
+// parseHistoryLine 解析历史文件中的一行，命令中的换行与反斜杠已被转义
 func parseHistoryLine(line string) (HistoryItem, error) {
+	item, err := splitHistoryLine(line)
+	if err != nil {
+		return item, err
+	}
+	item.Command = unescapeHistoryCommand(item.Command)
+	return item, nil
+}
+
+// splitHistoryLine 拆分 : 时间戳:耗时;命令，命令保持原样
+func splitHistoryLine(line string) (HistoryItem, error) {
 	if !strings.HasPrefix(line, ": ") {
 		return HistoryItem{}, fmt.Errorf("历史记录格式不正确: %s", line)
 	}
@@ -66,7 +64,7 @@ func parseHistoryLine(line string) (HistoryItem, error) {
 	return HistoryItem{
 		Timestamp:       timestamp,
 		DurationSeconds: duration,
-		Command:         unescapeHistoryCommand(commandPart),
+		Command:         commandPart,
 	}, nil
 }
 
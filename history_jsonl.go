@@ -0,0 +1,34 @@
+package prompt
+
+import (
+	"encoding/json"
+	"io"
+)
+
+var jsonlHistoryCodec = historyCodec{decode: decodeJSONLHistoryItem, format: formatJSONLHistoryItem}
+
+// NewJSONLHistoryStore 创建 JSON Lines 格式的历史后端，每行一个 JSON 对象，可以保存 Meta
+func NewJSONLHistoryStore(path string) (*FileHistoryStore, error) {
+	return newFileHistoryStore(path, jsonlHistoryCodec)
+}
+
+func formatJSONLHistoryItem(item HistoryItem) string {
+	// json.Marshal 会转义换行，保证每条历史占一行
+	data, err := json.Marshal(item)
+	if err != nil {
+		logger.Warnf("编码历史记录失败: %v", err)
+		return ""
+	}
+	return string(data) + "\n"
+}
+
+// parseJSONLHistoryItems 逐行解析 JSON Lines 格式的历史，跳过无法解析的行
+func parseJSONLHistoryItems(r io.Reader) ([]HistoryItem, error) {
+	return jsonlHistoryCodec.parse(r)
+}
+
+func decodeJSONLHistoryItem(line string) (HistoryItem, error) {
+	var item HistoryItem
+	err := json.Unmarshal([]byte(line), &item)
+	return item, err
+}
@@ -0,0 +1,105 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HistoryDuplicates 重复命令的处理方式
+type HistoryDuplicates int
+
+const (
+	// HistoryKeepDuplicates 保留所有重复命令
+	HistoryKeepDuplicates HistoryDuplicates = iota
+	// HistoryIgnoreConsecutive 与上一条相同时不记录，对应 zsh 的 HIST_IGNORE_DUPS
+	HistoryIgnoreConsecutive
+	// HistoryEraseDuplicates 记录新命令时删除之前所有相同的命令，对应 zsh 的 HIST_IGNORE_ALL_DUPS
+	HistoryEraseDuplicates
+)
+
+// HistoryOptions 历史记录选项，零值表示不做任何限制
+type HistoryOptions struct {
+	// MaxEntries 内存中最多保留的条数，超出时丢弃最早的记录，0 表示不限制（HISTSIZE）
+	MaxEntries int
+	// MaxFileEntries 历史文件最多保留的条数，0 表示不限制（SAVEHIST）
+	MaxFileEntries int
+	// Duplicates 重复命令的处理方式
+	Duplicates HistoryDuplicates
+	// IgnoreSpace 不记录以空格开头的命令，用于输入不想留下记录的内容（HIST_IGNORE_SPACE）
+	IgnoreSpace bool
+	// IgnorePatterns 匹配任一正则的命令不记录
+	IgnorePatterns []*regexp.Regexp
+	// IgnoreBuiltins 不记录内置命令（如 /history、/help）
+	IgnoreBuiltins bool
+}
+
+// DefaultHistoryOptions 默认历史记录选项：各保留 10000 条，忽略连续重复与空格开头的命令
+func DefaultHistoryOptions() HistoryOptions {
+	return HistoryOptions{
+		MaxEntries:     10000,
+		MaxFileEntries: 10000,
+		Duplicates:     HistoryIgnoreConsecutive,
+		IgnoreSpace:    true,
+	}
+}
+
+// ignored 判断命令是否因选项被忽略，不判断内置命令
+func (o HistoryOptions) ignored(command string) bool {
+	if strings.TrimSpace(command) == "" {
+		return true
+	}
+	if o.IgnoreSpace && strings.HasPrefix(command, " ") {
+		return true
+	}
+	for _, re := range o.IgnorePatterns {
+		if re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// add 按重复处理方式与条数限制追加历史项，返回新的列表与是否追加
+func (o HistoryOptions) add(items []HistoryItem, item HistoryItem, max int) ([]HistoryItem, bool) {
+	switch o.Duplicates {
+	case HistoryIgnoreConsecutive:
+		if len(items) > 0 && items[len(items)-1].Command == item.Command {
+			return items, false
+		}
+	case HistoryEraseDuplicates:
+		kept := items[:0:0]
+		for _, it := range items {
+			if it.Command != item.Command {
+				kept = append(kept, it)
+			}
+		}
+		items = kept
+	}
+	items = append(items, item)
+	return trimHistoryItems(items, max), true
+}
+
+// trimHistoryItems 只保留最近的 max 条，max 小于等于 0 时不限制
+func trimHistoryItems(items []HistoryItem, max int) []HistoryItem {
+	if max <= 0 || len(items) <= max {
+		return items
+	}
+	return append(items[:0:0], items[len(items)-max:]...)
+}
+
+// HistoryOptions 设置历史记录选项
+func (m *Prompt) HistoryOptions(opts HistoryOptions) {
+	WithHistoryOptions(opts)(m)
+}
+
+// WithHistoryOptions 设置历史记录选项，默认为零值，即不做任何限制，
+// 需要类似 shell 的默认行为时使用 DefaultHistoryOptions()
+func WithHistoryOptions(opts HistoryOptions) Option {
+	return func(p *Prompt) {
+		p.historyMu.Lock()
+		p.historyOptions = opts
+		p.historyItems = trimHistoryItems(p.historyItems, opts.MaxEntries)
+		p.historyIndex = len(p.historyItems)
+		p.historyMu.Unlock()
+	}
+}
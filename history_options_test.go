@@ -0,0 +1,81 @@
+package prompt
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func historyCommands(items []HistoryItem) []string {
+	commands := make([]string, 0, len(items))
+	for _, item := range items {
+		commands = append(commands, item.Command)
+	}
+	return commands
+}
+
+func TestHistoryOptionsAdd(t *testing.T) {
+	tests := []struct {
+		opts HistoryOptions
+		max  int
+		want []string
+	}{
+		{HistoryOptions{}, 0, []string{"a", "b", "a", "a"}},
+		{HistoryOptions{Duplicates: HistoryIgnoreConsecutive}, 0, []string{"a", "b", "a"}},
+		{HistoryOptions{Duplicates: HistoryEraseDuplicates}, 0, []string{"b", "a"}},
+		{HistoryOptions{}, 2, []string{"a", "a"}},
+	}
+	for _, tt := range tests {
+		var items []HistoryItem
+		for _, command := range []string{"a", "b", "a", "a"} {
+			items, _ = tt.opts.add(items, HistoryItem{Command: command}, tt.max)
+		}
+		if got := historyCommands(items); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("add(%+v, max=%d) = %q, want %q", tt.opts, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryOptionsIgnored(t *testing.T) {
+	opts := HistoryOptions{
+		IgnoreSpace:    true,
+		IgnorePatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)password`)},
+	}
+	for command, want := range map[string]bool{
+		"  ":                  true,
+		" secret := 1":        true,
+		`p := "Password123"`:  true,
+		"fmt.Println(1)":      false,
+		"fmt.Println( 1 )   ": false,
+	} {
+		if got := opts.ignored(command); got != want {
+			t.Errorf("ignored(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestAppendHistoryItemWithOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	opts := HistoryOptions{
+		MaxEntries:     3,
+		MaxFileEntries: 2,
+		Duplicates:     HistoryEraseDuplicates,
+		IgnoreBuiltins: true,
+	}
+	p := NewPrompt(WithHistoryFile(path), WithHistoryOptions(opts))
+	for _, command := range []string{"a", "b", "/history", "c", "a", "d"} {
+		p.AppendHistoryItem(command, time.Now(), 0)
+	}
+	if got, want := historyCommands(p.historyItems), []string{"c", "a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyItems = %q, want %q", got, want)
+	}
+	items, err := readHistoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := historyCommands(items), []string{"a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history file = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// replaceHistoryFile 在同目录写入临时文件后重命名为 path，写入中断时不会留下不完整的历史。
+// file 为已加锁的历史文件，保持打开直到替换完成，等待锁的进程随后会重新打开新文件。
+func replaceHistoryFile(file *os.File, path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
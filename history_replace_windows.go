@@ -0,0 +1,18 @@
+//go:build windows
+
+package prompt
+
+import (
+	"os"
+)
+
+// replaceHistoryFile Windows 上无法替换仍被其他进程打开的文件，在文件锁内原地截断后写入。
+func replaceHistoryFile(file *os.File, path string, data []byte) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,167 @@
+package prompt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HistoryScope 使用 ↑/↓ 翻找历史时的范围，参考 zsh 的 per-directory-history。
+// 非全局范围只调整翻找顺序：先翻完范围内的历史，再继续翻找其他历史。
+type HistoryScope int
+
+const (
+	// HistoryScopeGlobal 按时间顺序翻找全部历史
+	HistoryScopeGlobal HistoryScope = iota
+	// HistoryScopeDirectory 优先翻找在当前工作目录执行的历史。
+	// 默认的 zsh 格式不保存工作目录，只对当前进程执行的历史生效
+	HistoryScopeDirectory
+	// HistoryScopeSession 优先翻找当前会话执行的历史
+	HistoryScopeSession
+)
+
+func (s HistoryScope) String() string {
+	switch s {
+	case HistoryScopeDirectory:
+		return "当前目录"
+	case HistoryScopeSession:
+		return "当前会话"
+	default:
+		return "全部"
+	}
+}
+
+// next 返回切换后的范围，按 全部 -> 当前目录 -> 当前会话 循环
+func (s HistoryScope) next() HistoryScope {
+	return (s + 1) % (HistoryScopeSession + 1)
+}
+
+// newSessionID 生成会话 ID，用于区分同时运行的多个进程
+func newSessionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// workingDir 返回当前工作目录，获取失败时为空
+func workingDir() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return cwd
+}
+
+// SessionID 返回当前会话 ID，记录在每条历史的 SessionID 中
+func (m *Prompt) SessionID() string {
+	return m.sessionID
+}
+
+// HistoryScope 设置翻找历史的范围
+func (m *Prompt) HistoryScope(scope HistoryScope) {
+	WithHistoryScope(scope)(m)
+}
+
+// ToggleHistoryScope 切换翻找历史的范围，并返回切换后的范围
+func (m *Prompt) ToggleHistoryScope() HistoryScope {
+	m.HistoryScope(m.historyScope.next())
+	return m.historyScope
+}
+
+// WithHistoryScope 设置翻找历史的范围，默认为 HistoryScopeGlobal
+func WithHistoryScope(scope HistoryScope) Option {
+	return func(p *Prompt) {
+		p.historyScope = scope
+		p.historyMu.Lock()
+		p.historyIndex = len(p.historyItems)
+		p.historyMu.Unlock()
+		if p.input != nil {
+			p.input.Model.Placeholder = p.historyScopePlaceholder()
+		}
+	}
+}
+
+// WithSessionID 设置会话 ID，默认每个 Prompt 随机生成
+func WithSessionID(id string) Option {
+	return func(p *Prompt) {
+		p.sessionID = id
+	}
+}
+
+// historyScopePlaceholder 非全局范围时在空输入框中提示当前范围
+func (m *Prompt) historyScopePlaceholder() string {
+	if m.historyScope == HistoryScopeGlobal {
+		return ""
+	}
+	return "历史范围: " + m.historyScope.String()
+}
+
+// historyNavigation 返回 ↑/↓ 翻找时的历史顺序，长度与 historyItems 相同，需持有 historyMu
+func (m *Prompt) historyNavigation() []HistoryItem {
+	var inScope func(item HistoryItem) bool
+	switch m.historyScope {
+	case HistoryScopeDirectory:
+		cwd := workingDir()
+		if cwd == "" {
+			return m.historyItems
+		}
+		inScope = func(item HistoryItem) bool { return item.Cwd == cwd }
+	case HistoryScopeSession:
+		inScope = func(item HistoryItem) bool { return item.SessionID == m.sessionID }
+	default:
+		return m.historyItems
+	}
+	return scopeHistoryItems(m.historyItems, inScope)
+}
+
+// scopeHistoryItems 将范围内的历史移到最后，使其最先被翻到，两部分各自保持时间顺序
+func scopeHistoryItems(items []HistoryItem, inScope func(item HistoryItem) bool) []HistoryItem {
+	scoped := make([]HistoryItem, 0, len(items))
+	others := make([]HistoryItem, 0, len(items))
+	for _, item := range items {
+		if inScope(item) {
+			scoped = append(scoped, item)
+		} else {
+			others = append(others, item)
+		}
+	}
+	return append(others, scoped...)
+}
+
+// restoreHistoryTags 完整重载后，为历史格式无法保存的 Cwd、SessionID 与 Meta
+// 保留内存中时间戳与命令都相同的历史项的值
+func restoreHistoryTags(loaded, current []HistoryItem) []HistoryItem {
+	type key struct {
+		ts      int64
+		command string
+	}
+	tagged := make(map[key]HistoryItem)
+	for _, item := range current {
+		if item.Cwd != "" || item.SessionID != "" || item.Meta != nil {
+			tagged[key{item.Timestamp, item.Command}] = item
+		}
+	}
+	if len(tagged) == 0 {
+		return loaded
+	}
+	for i, item := range loaded {
+		old, ok := tagged[key{item.Timestamp, item.Command}]
+		if !ok {
+			continue
+		}
+		if item.Cwd == "" {
+			loaded[i].Cwd = old.Cwd
+		}
+		if item.SessionID == "" {
+			loaded[i].SessionID = old.SessionID
+		}
+		if item.Meta == nil {
+			loaded[i].Meta = old.Meta
+		}
+	}
+	return loaded
+}
@@ -0,0 +1,74 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHistoryScopeNavigation(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPrompt(WithSessionID("s1"), WithHistoryStore(NewMemoryHistoryStore(
+		HistoryItem{Timestamp: 1, Command: "here-old", Cwd: cwd, SessionID: "s0"},
+		HistoryItem{Timestamp: 2, Command: "session", Cwd: "/elsewhere", SessionID: "s1"},
+		HistoryItem{Timestamp: 3, Command: "elsewhere", Cwd: "/elsewhere", SessionID: "s0"},
+	)))
+
+	up := func() string {
+		p.Update(tea.KeyMsg{Type: tea.KeyUp})
+		return p.Value()
+	}
+	tests := []struct {
+		scope HistoryScope
+		want  []string
+	}{
+		{HistoryScopeGlobal, []string{"elsewhere", "session", "here-old"}},
+		{HistoryScopeDirectory, []string{"here-old", "elsewhere", "session"}},
+		{HistoryScopeSession, []string{"session", "elsewhere", "here-old"}},
+	}
+	for _, tt := range tests {
+		p.HistoryScope(tt.scope)
+		for _, want := range tt.want {
+			if got := up(); got != want {
+				t.Errorf("%s: up = %q, want %q", tt.scope, got, want)
+			}
+		}
+		p.SetValue("")
+	}
+
+	p.HistoryScope(HistoryScopeGlobal)
+	p.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if p.historyScope != HistoryScopeDirectory || p.input.Model.Placeholder != "历史范围: 当前目录" {
+		t.Errorf("ctrl+t scope = %s, placeholder = %q", p.historyScope, p.input.Model.Placeholder)
+	}
+}
+
+// Test: 历史文件被其他进程重写后，zsh 格式无法保存的会话与目录依然保留
+func TestHistoryScopeAfterReload(t *testing.T) {
+	if NewPrompt().SessionID() == "" {
+		t.Error("SessionID() should be generated by default")
+	}
+	path := filepath.Join(t.TempDir(), "history")
+	p := NewPrompt(WithSessionID("s1"), WithHistoryFile(path))
+	p.AppendHistoryItem("mine", time.Unix(1, 0), 0)
+	if err := os.WriteFile(path, []byte(": 1:0;mine\n: 2:0;other\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.refreshHistoryItemsFromFile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.historyItems) != 2 || p.historyItems[0].SessionID != "s1" || p.historyItems[0].Cwd == "" {
+		t.Fatalf("historyItems = %+v", p.historyItems)
+	}
+	p.HistoryScope(HistoryScopeSession)
+	p.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := p.Value(); got != "mine" {
+		t.Errorf("up = %q, want mine", got)
+	}
+}
@@ -0,0 +1,406 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 记录到 HistoryItem.Meta 中的通用信息
+const (
+	HistoryMetaStatus     = "status"      // 输出状态，见 OutResult.Status
+	HistoryMetaOutputSize = "output_size" // 输出内容的字节数
+)
+
+// HistoryStore 历史记录的持久化后端，默认为 zsh 扩展格式的历史文件（见 WithHistoryFile）。
+// 多个 Prompt 或多个进程可以共享同一个后端，每个 Prompt 通过 Open 获得独立的加载进度。
+type HistoryStore interface {
+	// Append 追加一条历史，已保存的历史按 opts 的去重方式与 MaxFileEntries 处理
+	Append(item HistoryItem, opts HistoryOptions) error
+	// Load 加载历史。reload 为 true 时 items 为全部历史，否则为上次加载后其他写入方新增的历史
+	Load() (items []HistoryItem, reload bool, err error)
+	// Search 按时间顺序返回 match 为 true 的历史，match 为 nil 时返回全部
+	Search(match func(HistoryItem) bool) ([]HistoryItem, error)
+	// Update 在写锁内读取全部历史，update 返回 changed 为 true 时用 updated 替换全部历史。
+	// 裁剪、删除、导入等先读后写的修改都需要通过 Update 完成，避免覆盖其他写入方的修改
+	Update(update func(items []HistoryItem) (updated []HistoryItem, changed bool)) error
+	// Open 返回共享同一份历史、但 Load 的进度独立的后端，通过它写入的内容不会被它自己再次加载
+	Open() HistoryStore
+}
+
+// TrimHistory 只保留后端中最近的 max 条历史
+func TrimHistory(store HistoryStore, max int) error {
+	return store.Update(func(items []HistoryItem) ([]HistoryItem, bool) {
+		trimmed := trimHistoryItems(items, max)
+		return trimmed, len(trimmed) != len(items)
+	})
+}
+
+// DeleteHistory 删除后端中第一条时间戳与命令都相同的历史
+func DeleteHistory(store HistoryStore, item HistoryItem) error {
+	return store.Update(func(items []HistoryItem) ([]HistoryItem, bool) {
+		remained := removeHistoryItem(items, item)
+		return remained, len(remained) != len(items)
+	})
+}
+
+// historyCodec 历史文件的编码方式，每条历史占一行
+type historyCodec struct {
+	decode func(line string) (HistoryItem, error)
+	format func(item HistoryItem) string
+}
+
+var zshHistoryCodec = historyCodec{decode: parseHistoryLine, format: formatHistoryItem}
+
+// parse 逐行解析历史，跳过无法解析的行
+func (c historyCodec) parse(r io.Reader) ([]HistoryItem, error) {
+	items, _, err := c.parseLines(r)
+	return items, err
+}
+
+// parseLines 逐行解析历史，同时返回无法解析的行，重写文件时原样保留。
+// 有无法解密的行且没有任何一行能解密时认为密钥错误，返回 errHistoryDecrypt
+func (c historyCodec) parseLines(r io.Reader) (items []HistoryItem, invalid []string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	items = make([]HistoryItem, 0)
+	var lastErr, decryptErr error
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		item, err := c.decode(strings.TrimSpace(line))
+		if err != nil {
+			if errors.Is(err, errHistoryDecrypt) {
+				decryptErr = err
+			}
+			lastErr = err
+			invalid = append(invalid, line)
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(items) == 0 && decryptErr != nil {
+		return nil, nil, decryptErr
+	}
+	if len(invalid) > 0 {
+		logger.Warnf("跳过 %d 行无法解析的历史: %v", len(invalid), lastErr)
+	}
+	return items, invalid, nil
+}
+
+// FileHistoryStore 基于文件的历史后端，写入时锁定历史文件保证多进程安全，加载时只读取新追加的内容。
+// 整体重写时先写入临时文件再替换历史文件（Windows 无法替换已打开的文件，在锁内原地重写），
+// 等待文件锁的进程发现文件已被替换时重新打开并加锁。
+// 旧版本等到锁后不会重新打开，其紧接在重写之后的追加可能写入已被替换的旧文件。
+type FileHistoryStore struct {
+	path  string
+	codec historyCodec
+	sync  *historyFileSync
+}
+
+// NewZshHistoryStore 创建 zsh 扩展格式（: 时间戳:耗时;命令）的历史后端，不支持保存 Meta
+func NewZshHistoryStore(path string) (*FileHistoryStore, error) {
+	return newFileHistoryStore(path, zshHistoryCodec)
+}
+
+func newFileHistoryStore(path string, codec historyCodec) (*FileHistoryStore, error) {
+	resolved, err := resolveHistoryFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileHistoryStore{
+		path:  resolved,
+		codec: codec,
+		sync:  newHistoryFileSync(resolved, codec.parse),
+	}, nil
+}
+
+// Path 返回历史文件的绝对路径
+func (s *FileHistoryStore) Path() string {
+	return s.path
+}
+
+// Append 追加一条历史。忽略连续重复时只读取文件的最后一行，
+// 删除所有重复时需要解析整个文件，超出 MaxFileEntries 时整体重写
+func (s *FileHistoryStore) Append(item HistoryItem, opts HistoryOptions) error {
+	return s.update(func(file *os.File, lines func() (int, error)) ([]byte, bool, error) {
+		switch opts.Duplicates {
+		case HistoryEraseDuplicates:
+			return s.rewrite(file, item, opts)
+		case HistoryIgnoreConsecutive:
+			last, err := lastHistoryLine(file)
+			if err != nil {
+				return nil, false, err
+			}
+			if prev, err := s.codec.decode(strings.TrimSpace(last)); err == nil && prev.Command == item.Command {
+				return nil, false, nil
+			}
+		}
+		if opts.MaxFileEntries > 0 {
+			count, err := lines()
+			if err != nil {
+				return nil, false, err
+			}
+			if count >= opts.MaxFileEntries {
+				return s.rewrite(file, item, opts)
+			}
+		}
+		return s.append(file, item)
+	})
+}
+
+func (s *FileHistoryStore) Load() ([]HistoryItem, bool, error) {
+	return s.sync.read()
+}
+
+func (s *FileHistoryStore) Search(match func(HistoryItem) bool) ([]HistoryItem, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return []HistoryItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	items, err := s.codec.parse(file)
+	if err != nil {
+		return nil, err
+	}
+	return filterHistoryItems(items, match), nil
+}
+
+func (s *FileHistoryStore) Update(update func(items []HistoryItem) ([]HistoryItem, bool)) error {
+	return s.update(func(file *os.File, _ func() (int, error)) ([]byte, bool, error) {
+		items, invalid, err := s.codec.parseLines(file)
+		if err != nil {
+			return nil, false, err
+		}
+		updated, changed := update(items)
+		if !changed {
+			return nil, false, nil
+		}
+		return s.overwrite(file, updated, invalid)
+	})
+}
+
+// Open 返回同一历史文件的新后端，读取进度从头开始
+func (s *FileHistoryStore) Open() HistoryStore {
+	return &FileHistoryStore{
+		path:  s.path,
+		codec: s.codec,
+		sync:  newHistoryFileSync(s.path, s.codec.parse),
+	}
+}
+
+// update 锁定历史文件并执行写入，lines 返回文件中非空行的数量，只统计上次统计后追加的内容
+func (s *FileHistoryStore) update(write func(file *os.File, lines func() (int, error)) (data []byte, rewritten bool, err error)) error {
+	file, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	defer func() {
+		_ = unlockFile(file)
+	}()
+	return s.sync.write(file, func(lines func() (int, error)) ([]byte, bool, error) {
+		return write(file, lines)
+	})
+}
+
+// lock 以读写方式打开历史文件并加锁。等待锁期间文件可能被其他进程整体重写替换，这时重新打开
+func (s *FileHistoryStore) lock() (*os.File, error) {
+	for {
+		if err := ensureHistoryFile(s.path); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(s.path, os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+		opened, err := file.Stat()
+		if err == nil {
+			var current os.FileInfo
+			if current, err = os.Stat(s.path); err == nil && os.SameFile(opened, current) {
+				return file, nil
+			}
+		}
+		_ = unlockFile(file)
+		file.Close()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// append 在文件末尾追加一条历史，返回写入的内容
+func (s *FileHistoryStore) append(file *os.File, item HistoryItem) ([]byte, bool, error) {
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return nil, false, err
+	}
+	data := []byte(s.codec.format(item))
+	if _, err := file.Write(data); err != nil {
+		return nil, false, err
+	}
+	return data, false, file.Sync()
+}
+
+// rewrite 解析整个文件后追加历史项，有记录被删除时整体重写文件
+func (s *FileHistoryStore) rewrite(file *os.File, item HistoryItem, opts HistoryOptions) ([]byte, bool, error) {
+	items, invalid, err := s.codec.parseLines(file)
+	if err != nil {
+		return nil, false, err
+	}
+	updated, added := opts.add(items, item, opts.MaxFileEntries)
+	if !added {
+		return nil, false, nil
+	}
+	if len(updated) == len(items)+1 {
+		return s.append(file, item)
+	}
+	return s.overwrite(file, updated, invalid)
+}
+
+// overwrite 用全部历史替换历史文件，见 replaceHistoryFile。
+// 无法解析的行 invalid 原样写在最前面，避免因为格式或密钥问题丢失历史
+func (s *FileHistoryStore) overwrite(file *os.File, items []HistoryItem, invalid []string) ([]byte, bool, error) {
+	var b strings.Builder
+	for _, line := range invalid {
+		b.WriteString(line + "\n")
+	}
+	for _, item := range items {
+		b.WriteString(s.codec.format(item))
+	}
+	data := []byte(b.String())
+	if err := replaceHistoryFile(file, s.path, data); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// lastHistoryLine 从文件末尾向前读取最后一个非空行
+func lastHistoryLine(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	const chunkSize = 4096
+	var data []byte
+	for offset := info.Size(); offset > 0; {
+		n := min(chunkSize, offset)
+		offset -= n
+		chunk := make([]byte, n)
+		if _, err := file.ReadAt(chunk, offset); err != nil {
+			return "", err
+		}
+		data = append(chunk, data...)
+		line := bytes.TrimRight(data, "\r\n")
+		if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+			return string(line[i+1:]), nil
+		}
+		if offset == 0 {
+			return string(line), nil
+		}
+	}
+	return "", nil
+}
+
+// MemoryHistoryStore 内存中的历史后端，用于测试或不需要持久化的场景
+type MemoryHistoryStore struct {
+	history *memoryHistory
+	loaded  int // 上次加载时的 version，由 history.mu 保护
+}
+
+// memoryHistory Open 得到的各个 MemoryHistoryStore 共享的历史
+type memoryHistory struct {
+	mu      sync.Mutex
+	items   []HistoryItem
+	version int // 每次修改递增
+}
+
+// NewMemoryHistoryStore 创建内存历史后端，items 为初始历史
+func NewMemoryHistoryStore(items ...HistoryItem) *MemoryHistoryStore {
+	return &MemoryHistoryStore{history: &memoryHistory{items: items, version: 1}}
+}
+
+func (s *MemoryHistoryStore) Append(item HistoryItem, opts HistoryOptions) error {
+	h := s.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var added bool
+	if h.items, added = opts.add(h.items, item, opts.MaxFileEntries); added {
+		h.version++
+	}
+	return nil
+}
+
+// Load 历史有变化时返回全部历史
+func (s *MemoryHistoryStore) Load() ([]HistoryItem, bool, error) {
+	h := s.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.loaded == h.version {
+		return nil, false, nil
+	}
+	s.loaded = h.version
+	return append([]HistoryItem{}, h.items...), true, nil
+}
+
+func (s *MemoryHistoryStore) Search(match func(HistoryItem) bool) ([]HistoryItem, error) {
+	h := s.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return filterHistoryItems(h.items, match), nil
+}
+
+func (s *MemoryHistoryStore) Update(update func(items []HistoryItem) ([]HistoryItem, bool)) error {
+	h := s.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if updated, changed := update(append([]HistoryItem{}, h.items...)); changed {
+		h.items = updated
+		h.version++
+	}
+	return nil
+}
+
+// Open 返回共享同一份历史的新后端，读取进度从头开始
+func (s *MemoryHistoryStore) Open() HistoryStore {
+	return &MemoryHistoryStore{history: s.history}
+}
+
+func filterHistoryItems(items []HistoryItem, match func(HistoryItem) bool) []HistoryItem {
+	result := make([]HistoryItem, 0, len(items))
+	for _, item := range items {
+		if match == nil || match(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// historyMeta 返回记录到历史项中的附加信息
+func historyMeta(result OutResult) map[string]string {
+	meta := make(map[string]string, len(result.Meta)+2)
+	for k, v := range result.Meta {
+		meta[k] = v
+	}
+	if result.Status != "" {
+		meta[HistoryMetaStatus] = result.Status
+	}
+	meta[HistoryMetaOutputSize] = strconv.Itoa(len(result.Text))
+	return meta
+}
@@ -0,0 +1,118 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLHistoryStore(t *testing.T) {
+	store, err := NewJSONLHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []HistoryItem{
+		{Timestamp: 1, DurationSeconds: 2, Command: "for i := 0; i < 3; i++ {\n\tfmt.Println(i)\n}"},
+		{Timestamp: 3, Command: "x", Meta: map[string]string{"cwd": "/tmp", HistoryMetaStatus: "exit status 1"}},
+		{Timestamp: 4, Command: "y"},
+	}
+	for _, item := range items {
+		if err := store.Append(item, HistoryOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := store.Search(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, items) {
+		t.Errorf("Search(nil) = %+v, want %+v", got, items)
+	}
+
+	got, err = store.Search(func(item HistoryItem) bool { return strings.Contains(item.Command, "fmt") })
+	if err != nil || len(got) != 1 || got[0].Timestamp != 1 {
+		t.Errorf("Search(fmt) = %+v, %v", got, err)
+	}
+
+	if err := TrimHistory(store, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Search(nil); !reflect.DeepEqual(historyCommands(got), []string{"y"}) {
+		t.Errorf("TrimHistory(1) = %q, want [y]", historyCommands(got))
+	}
+}
+
+func TestMemoryHistoryStore(t *testing.T) {
+	store := NewMemoryHistoryStore(HistoryItem{Command: "a"})
+	p := NewPrompt(WithHistoryStore(store))
+	p.AppendHistoryItem("b", time.Now(), 0)
+
+	// 另一个 Prompt 共享同一个后端，各自的加载进度互不影响
+	other := NewPrompt(WithHistoryStore(store))
+	other.AppendHistoryItem("c", time.Now(), 0)
+	for _, m := range []*Prompt{p, other} {
+		if err := m.refreshHistoryItemsFromFile(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := historyCommands(m.historyItems), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("historyItems = %q, want %q", got, want)
+		}
+		if _, reload, _ := m.historyStore.Load(); reload {
+			t.Errorf("Load() without changes should not reload")
+		}
+	}
+}
+
+// Test: 忽略连续重复时与文件最后一行比较，超出 MaxFileEntries 时替换文件后仍能增量加载
+func TestFileHistoryStoreAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	store, err := NewZshHistoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendHistoryLines(t, path, ": 1:0;a\n: 2:0;b\n\n")
+	if _, _, err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	opts := HistoryOptions{Duplicates: HistoryIgnoreConsecutive, MaxFileEntries: 3}
+	for i, command := range []string{"b", "c", "c", "d"} {
+		if err := store.Append(HistoryItem{Timestamp: int64(i + 3), Command: command}, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := readHistoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := historyCommands(items), []string{"b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history file = %q, want %q", got, want)
+	}
+	if items, reload, err := store.Load(); err != nil || reload || len(items) != 0 {
+		t.Errorf("Load() after own writes = %q, %v, %v", historyCommands(items), reload, err)
+	}
+	appendHistoryLines(t, path, ": 9:0;other\n")
+	if items, reload, _ := store.Load(); reload || !reflect.DeepEqual(historyCommands(items), []string{"other"}) {
+		t.Errorf("Load() after replace = %q, %v, want [other], false", historyCommands(items), reload)
+	}
+
+	// 行数按增量统计：其他进程追加后依然按 MaxFileEntries 裁剪，且不使用额外的锁文件
+	appendHistoryLines(t, path, ": 10:0;x\n")
+	if store.sync.lines != 3 {
+		t.Errorf("counted lines = %d, want 3 before counting the new append", store.sync.lines)
+	}
+	if err := store.Append(HistoryItem{Timestamp: 11, Command: "y"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := readHistoryFile(path); !reflect.DeepEqual(historyCommands(items), []string{"other", "x", "y"}) {
+		t.Errorf("history file = %q, want [other x y]", historyCommands(items))
+	}
+	if store.sync.lines != 3 {
+		t.Errorf("counted lines = %d, want 3", store.sync.lines)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file should not be created: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// historySyncTailSize 记录已读内容末尾的字节数，用于发现文件被原地重写
+const historySyncTailSize = 256
+
+// historyFileCursor 记录历史文件已处理到的位置。
+// 文件被替换（inode 变化）、截断或原地重写时需要从头处理。
+type historyFileCursor struct {
+	info   os.FileInfo // 上次处理时的文件信息，为 nil 表示需要从头处理
+	offset int64       // 已处理到的位置，总是位于行尾
+	tail   []byte      // offset 之前的最后一段内容
+}
+
+// historyFileSync 记录历史文件的读取进度，刷新时只读取其他进程新追加的行，
+// 同时记录文件的行数，追加时只统计新追加的内容。
+type historyFileSync struct {
+	mu     sync.Mutex
+	path   string
+	parse  func(r io.Reader) ([]HistoryItem, error)
+	loaded historyFileCursor // 读取进度
+	count  historyFileCursor // 行数统计的进度，与读取进度分开，写入前不需要先读取其他进程追加的内容
+	lines  int               // count.offset 之前的非空行数
+}
+
+func newHistoryFileSync(path string, parse func(r io.Reader) ([]HistoryItem, error)) *historyFileSync {
+	return &historyFileSync{path: path, parse: parse}
+}
+
+// read 读取历史文件的变化。reload 为 true 时 items 为文件的全部内容，否则为新追加的内容
+func (s *historyFileSync) read() (items []HistoryItem, reload bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		if s.loaded.info == nil {
+			return nil, false, nil
+		}
+		s.loaded.reset()
+		return []HistoryItem{}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !s.loaded.unchanged(file, info) {
+		s.loaded.reset()
+		reload = true
+	} else if info.Size() == s.loaded.offset {
+		return nil, false, nil
+	}
+	data, err := readHistoryLines(file, s.loaded.offset, info.Size())
+	if err != nil {
+		return nil, false, err
+	}
+	items, err = s.parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	s.loaded.advance(info, data)
+	return items, reload, nil
+}
+
+// write 在持有文件锁时执行写入并更新读取进度。
+// write 返回写入的内容，rewritten 表示文件被整体重写为该内容，lines 返回写入前文件中非空行的数量。
+// 写入前已读到文件末尾时跳过本进程写入的内容，否则下次读取时完整重载，避免重复。
+func (s *historyFileSync) write(file *os.File, write func(lines func() (int, error)) (data []byte, rewritten bool, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	synced := s.loaded.unchanged(file, info) && info.Size() == s.loaded.offset
+	data, rewritten, err := write(func() (int, error) { return s.countLines(file, info) })
+	if err != nil {
+		s.loaded.reset()
+		s.count.reset()
+		return err
+	}
+	// 整体重写时历史文件已被替换，需要读取新文件的信息
+	if info, err = os.Stat(s.path); err != nil {
+		s.loaded.reset()
+		s.count.reset()
+		return err
+	}
+	switch {
+	case rewritten:
+		s.loaded.reset()
+		s.loaded.advance(info, data)
+		s.count.reset()
+		s.count.advance(info, data)
+		s.lines = countHistoryLines(data)
+	case synced:
+		s.loaded.advance(info, data)
+	default:
+		s.loaded.reset()
+	}
+	return nil
+}
+
+// countLines 返回文件中非空行的数量，只统计上次统计后追加的完整行，文件被替换或重写时重新统计
+func (s *historyFileSync) countLines(file *os.File, info os.FileInfo) (int, error) {
+	if !s.count.unchanged(file, info) {
+		s.count.reset()
+		s.lines = 0
+	}
+	data, err := readHistoryLines(file, s.count.offset, info.Size())
+	if err != nil {
+		s.count.reset()
+		return 0, err
+	}
+	s.lines += countHistoryLines(data)
+	s.count.advance(info, data)
+	return s.lines, nil
+}
+
+// readHistoryLines 读取 [offset, size) 中完整的行，未写完的行留到下次读取，不改变文件的读写位置
+func readHistoryLines(file *os.File, offset, size int64) ([]byte, error) {
+	data, err := io.ReadAll(io.NewSectionReader(file, offset, size-offset))
+	if err != nil {
+		return nil, err
+	}
+	return data[:bytes.LastIndexByte(data, '\n')+1], nil
+}
+
+// countHistoryLines 统计非空行的数量，每条历史占一行
+func countHistoryLines(data []byte) int {
+	count := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// unchanged 判断文件是否仍是上次处理的文件，且已处理的内容没有被修改
+func (c *historyFileCursor) unchanged(file *os.File, info os.FileInfo) bool {
+	if c.info == nil || !os.SameFile(c.info, info) || info.Size() < c.offset {
+		return false
+	}
+	if len(c.tail) == 0 {
+		return true
+	}
+	buf := make([]byte, len(c.tail))
+	if _, err := file.ReadAt(buf, c.offset-int64(len(buf))); err != nil {
+		return false
+	}
+	return bytes.Equal(buf, c.tail)
+}
+
+func (c *historyFileCursor) advance(info os.FileInfo, data []byte) {
+	c.info = info
+	c.offset += int64(len(data))
+	tail := append(c.tail[:len(c.tail):len(c.tail)], data...)
+	if len(tail) > historySyncTailSize {
+		tail = tail[len(tail)-historySyncTailSize:]
+	}
+	c.tail = append([]byte(nil), tail...)
+}
+
+func (c *historyFileCursor) reset() {
+	c.info = nil
+	c.offset = 0
+	c.tail = nil
+}
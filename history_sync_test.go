@@ -0,0 +1,84 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func appendHistoryLines(t *testing.T, path string, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryFileSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	appendHistoryLines(t, path, ": 1:0;a\n: 2:0;b\n")
+	s := newHistoryFileSync(path, parseHistoryItems)
+
+	check := func(name string, wantReload bool, want ...string) {
+		t.Helper()
+		items, reload, err := s.read()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := historyCommands(items); reload != wantReload || !reflect.DeepEqual(got, append([]string{}, want...)) {
+			t.Errorf("%s: read() = %q, %v, want %q, %v", name, got, reload, want, wantReload)
+		}
+	}
+
+	check("首次读取", true, "a", "b")
+	check("没有变化", false)
+	appendHistoryLines(t, path, ": 3:0;c\n: 4:0;d")
+	check("追加并忽略未写完的行", false, "c")
+	appendHistoryLines(t, path, "\n")
+	check("补全行", false, "d")
+
+	// 原地重写为更长的内容
+	if err := os.WriteFile(path, []byte(": 5:0;x\n: 6:0;y\n: 7:0;z\n: 8:0;w\n: 9:0;v\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	check("原地重写", true, "x", "y", "z", "w", "v")
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	check("截断", true)
+
+	// 轮转：新文件替换旧文件
+	rotated := path + ".new"
+	appendHistoryLines(t, rotated, ": 10:0;r\n")
+	if err := os.Rename(rotated, path); err != nil {
+		t.Fatal(err)
+	}
+	check("轮转", true, "r")
+}
+
+func TestRefreshHistorySkipsOwnWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	p := NewPrompt(WithHistoryFile(path), WithHistoryOptions(HistoryOptions{}))
+	p.AppendHistoryItem("a", time.Now(), 0)
+	if err := p.refreshHistoryItemsFromFile(); err != nil {
+		t.Fatal(err)
+	}
+	p.AppendHistoryItem("b", time.Now(), 0)
+	appendHistoryLines(t, path, ": 1:0;other\n")
+	if err := p.refreshHistoryItemsFromFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := historyCommands(p.historyItems), []string{"a", "b", "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("historyItems = %q, want %q", got, want)
+	}
+	if p.historyIndex != len(p.historyItems) {
+		t.Errorf("historyIndex = %d, want %d", p.historyIndex, len(p.historyItems))
+	}
+}
@@ -30,6 +30,10 @@ type Input struct {
 	Model textinput.Model
 	Style lipgloss.Style
 
+	// 语法高亮，Highlighter 为 nil 时使用 textinput 默认渲染
+	Highlighter Highlighter
+	Theme       Theme
+
 	KeyMap CompletionKeyMap
 }
 
@@ -38,7 +42,32 @@ func (m Input) Init() tea.Cmd {
 }
 
 func (m Input) View() string {
-	return m.Model.View()
+	if m.Highlighter == nil {
+		return m.Model.View()
+	}
+	// 占位符、密码模式以及需要横向滚动的情况交给 textinput 处理
+	if m.Model.Value() == "" || m.Model.EchoMode != textinput.EchoNormal || m.Model.Width > 0 {
+		return m.Model.View()
+	}
+	return m.highlightView()
+}
+
+// highlightView 按高亮区间渲染输入内容，光标沿用 textinput 的光标样式
+func (m Input) highlightView() string {
+	value := m.Model.Value()
+	theme := m.Theme
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	tokens := m.Highlighter.Highlight(value)
+	renderCursor := func(char string, kind TokenKind) string {
+		c := m.Model.Cursor
+		c.TextStyle = theme.Style(kind)
+		c.SetChar(char)
+		return c.View()
+	}
+	text := renderHighlighted(value, tokens, theme, m.Model.Position(), renderCursor)
+	return m.Model.PromptStyle.Render(m.Model.Prompt) + text
 }
 
 func (m *Input) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
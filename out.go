@@ -1,6 +1,8 @@
 package prompt
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -13,9 +15,10 @@ func NewOut(text string) *Out {
 	vp := viewport.New(w, h)
 	vp.SetContent(text)
 	m := &Out{
-		Model:  vp,
-		Style:  BaseFocusStyle,
-		KeyMap: DefaultCompletionKeyMap(),
+		Model:       vp,
+		Style:       BaseFocusStyle,
+		StatusStyle: OutStatusStyle,
+		KeyMap:      DefaultCompletionKeyMap(),
 	}
 	return m
 }
@@ -24,6 +27,9 @@ type Out struct {
 	BaseModel
 	Model viewport.Model
 	Style lipgloss.Style
+	// Status 显示在输出底部的状态，如程序被终止的原因
+	Status      string
+	StatusStyle lipgloss.Style
 
 	KeyMap CompletionKeyMap
 }
@@ -33,7 +39,14 @@ func (m Out) Init() tea.Cmd {
 }
 
 func (m Out) View() string {
-	return m.Model.View()
+	if m.Status == "" {
+		return m.Model.View()
+	}
+	status := m.StatusStyle.Render(m.Status)
+	if strings.TrimSpace(m.Model.View()) == "" {
+		return status
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.Model.View(), status)
 }
 
 func (m *Out) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -0,0 +1,51 @@
+package prompt
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// closeOverlayMsg 关闭当前浮层
+type closeOverlayMsg struct{}
+
+// CloseOverlay 浮层返回该命令以关闭自身
+func CloseOverlay() tea.Msg {
+	return closeOverlayMsg{}
+}
+
+// OpenOverlay 在输入框下方打开浮层，浮层打开期间接管所有按键，
+// 通过返回 CloseOverlay 命令关闭
+func (m *Prompt) OpenOverlay(overlay tea.Model) tea.Cmd {
+	m.completion = nil
+	m.completionSelectOverride = nil
+	m.overlay = overlay
+	return overlay.Init()
+}
+
+// CloseOverlay 关闭当前浮层
+func (m *Prompt) CloseOverlay() {
+	m.overlay = nil
+}
+
+// HasOverlay 是否有打开的浮层
+func (m *Prompt) HasOverlay() bool {
+	return m.overlay != nil
+}
+
+// updateOverlay 将消息交给浮层处理，返回 false 表示消息需要继续由 Prompt 处理
+func (m *Prompt) updateOverlay(msg tea.Msg) (tea.Cmd, bool) {
+	if _, ok := msg.(closeOverlayMsg); ok {
+		m.overlay = nil
+		return nil, true
+	}
+	if m.overlay == nil {
+		return nil, false
+	}
+	overlay, cmd := m.overlay.Update(msg)
+	m.overlay = overlay
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		return cmd, true
+	}
+	// 其他消息（窗口大小、列表的过滤结果、状态消息超时等）同时交给浮层与 Prompt
+	return cmd, false
+}
@@ -0,0 +1,91 @@
+package prompt
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultPagerHeight 未获取到终端高度时分页浮层的行数
+const defaultPagerHeight = 20
+
+var pagerFooterStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("246"))
+
+// PagerOverlay 分页查看长文本的浮层，滚动使用 viewport 的默认快捷键（↑/↓、pgup/pgdown、空格等）
+type PagerOverlay struct {
+	Viewport viewport.Model
+	Help     help.Model
+	Top      key.Binding
+	Bottom   key.Binding
+	Close    key.Binding
+	title    string
+}
+
+// NewPagerOverlay 创建分页浮层，height 为内容区域的行数，小于等于 0 时使用默认值
+func NewPagerOverlay(title, content string, width, height int) *PagerOverlay {
+	if height <= 0 {
+		height = defaultPagerHeight
+	}
+	height = min(height, max(lipgloss.Height(content), 1))
+	vp := viewport.New(width, height)
+	vp.SetContent(content)
+	return &PagerOverlay{
+		Viewport: vp,
+		Help:     help.New(),
+		Top: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g", "顶部"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G", "底部"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "关闭"),
+		),
+		title: title,
+	}
+}
+
+func (m *PagerOverlay) Init() tea.Cmd {
+	return nil
+}
+
+func (m *PagerOverlay) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.Close):
+			return m, CloseOverlay
+		case key.Matches(msg, m.Top):
+			m.Viewport.GotoTop()
+			return m, nil
+		case key.Matches(msg, m.Bottom):
+			m.Viewport.GotoBottom()
+			return m, nil
+		}
+	case tea.WindowSizeMsg:
+		m.Viewport.Width = msg.Width
+		m.Help.Width = msg.Width
+	}
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *PagerOverlay) View() string {
+	vk := m.Viewport.KeyMap
+	footer := fmt.Sprintf("%3.f%%  ", m.Viewport.ScrollPercent()*100) +
+		m.Help.ShortHelpView([]key.Binding{vk.Down, vk.Up, vk.PageDown, vk.PageUp, m.Top, m.Bottom, m.Close})
+	return BaseFocusStyle.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		helpTitleStyle.Render(m.title),
+		m.Viewport.View(),
+		pagerFooterStyle.Render(footer),
+	))
+}
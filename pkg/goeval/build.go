@@ -0,0 +1,249 @@
+package goeval
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	buildModule    = "goeval"
+	binDir         = "bin"
+	defaultMaxBins = 16
+)
+
+// Timings 单次执行各阶段的耗时
+type Timings struct {
+	Format time.Duration
+	Build  time.Duration
+	Run    time.Duration
+	// Cached 为 true 时表示复用了已编译的二进制，没有重新编译
+	Cached bool
+}
+
+func (t Timings) String() string {
+	build := t.Build.Round(time.Millisecond).String()
+	if t.Cached {
+		build = "cached"
+	}
+	return fmt.Sprintf("format %s, build %s, run %s",
+		t.Format.Round(time.Millisecond), build, t.Run.Round(time.Millisecond))
+}
+
+// TimingRunner 可以报告最近一次执行耗时的 Runner
+type TimingRunner interface {
+	Runner
+	Timings() Timings
+}
+
+// BuildRunner 在持久化的模块目录中通过 `go build -o` 编译并运行源码。
+//
+// 目录中保留 go.mod，配合 Go 自身的构建缓存保证增量编译，
+// 编译产物按源码与编译参数的哈希缓存在 bin 目录下，相同的程序不会重复编译。
+type BuildRunner struct {
+	// Dir 模块目录，为空时使用临时目录
+	Dir string
+	// Toolchain go 命令的路径，为空时使用 PATH 中的 go
+	Toolchain string
+	// BuildFlags 额外的编译参数，如 -race、-tags=foo
+	BuildFlags []string
+	// Proxy 覆盖 GOPROXY，离线时可设为 off（只使用模块缓存）或 file:// 开头的本地代理
+	Proxy string
+	// MaxBinaries 缓存的二进制数量上限，超出时删除最久未使用的
+	MaxBinaries int
+	// Sandbox 运行时的资源与权限限制，为 nil 时不做限制
+	Sandbox *Sandbox
+
+	timings  Timings
+	importer *moduleImporter
+}
+
+func NewBuildRunner(dir string) *BuildRunner {
+	return &BuildRunner{
+		Dir:         dir,
+		Toolchain:   "go",
+		MaxBinaries: defaultMaxBins,
+	}
+}
+
+// Importer 返回在模块目录中导入依赖的 importer，供会话做类型检查
+func (r *BuildRunner) Importer() types.Importer {
+	if r.importer == nil {
+		r.importer = &moduleImporter{runner: r}
+	}
+	return r.importer
+}
+
+// Timings 返回最近一次执行的耗时
+func (r *BuildRunner) Timings() Timings {
+	return r.timings
+}
+
+func (r *BuildRunner) Run(ctx context.Context, src string) (string, error) {
+	r.timings = Timings{}
+	if err := r.prepare(ctx); err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	if formatted, err := format.Source([]byte(src)); err == nil {
+		src = string(formatted)
+	}
+	r.timings.Format = time.Since(start)
+
+	start = time.Now()
+	bin, out, err := r.build(ctx, src)
+	r.timings.Build = time.Since(start)
+	if err != nil {
+		return out, err
+	}
+
+	start = time.Now()
+	out, err = r.run(ctx, bin)
+	r.timings.Run = time.Since(start)
+	return out, err
+}
+
+func (r *BuildRunner) run(ctx context.Context, bin string) (string, error) {
+	var buf bytes.Buffer
+	if r.Sandbox == nil {
+		cmd := exec.CommandContext(ctx, bin)
+		cmd.Dir = r.Dir
+		// 标准输出与标准错误写入同一个缓冲区，保证输出顺序
+		cmd.Stdout = &buf
+		cmd.Stderr = &buf
+		if err := cmd.Run(); err != nil {
+			return buf.String(), fmt.Errorf("运行失败: %w", err)
+		}
+		return buf.String(), nil
+	}
+
+	cmd, runCtx, cancel, err := r.Sandbox.command(ctx, bin, r.Dir)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err = cmd.Run()
+	return buf.String(), r.Sandbox.termination(runCtx, err, buf.String())
+}
+
+// prepare 创建模块目录并初始化 go.mod
+func (r *BuildRunner) prepare(ctx context.Context) error {
+	if r.Dir == "" {
+		dir, err := os.MkdirTemp("", "goeval-")
+		if err != nil {
+			return fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		r.Dir = dir
+	}
+	if err := os.MkdirAll(filepath.Join(r.Dir, binDir), 0o755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(r.Dir, "go.mod")); err == nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, r.toolchain(), "mod", "init", buildModule)
+	cmd.Dir = r.Dir
+	cmd.Env = r.goEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go mod init 失败: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// build 编译源码并返回二进制路径，命中缓存时直接返回
+func (r *BuildRunner) build(ctx context.Context, src string) (string, string, error) {
+	codePath := filepath.Join(r.Dir, "main.go")
+	if err := os.WriteFile(codePath, []byte(src), 0o644); err != nil {
+		return "", "", fmt.Errorf("写入源码失败: %w", err)
+	}
+
+	bin := filepath.Join(r.Dir, binDir, r.hash(src))
+	if _, err := os.Stat(bin); err == nil {
+		r.timings.Cached = true
+		now := time.Now()
+		os.Chtimes(bin, now, now)
+		return bin, "", nil
+	}
+
+	args := append([]string{"build", "-o", bin}, r.BuildFlags...)
+	args = append(args, ".")
+	cmd := exec.CommandContext(ctx, r.toolchain(), args...)
+	cmd.Dir = r.Dir
+	cmd.Env = r.goEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", string(out), fmt.Errorf("go build 失败: %w", err)
+	}
+	r.prune()
+	return bin, "", nil
+}
+
+// hash 源码、依赖、工具链与编译参数共同决定二进制是否可以复用
+func (r *BuildRunner) hash(src string) string {
+	h := sha256.New()
+	h.Write([]byte(r.toolchain()))
+	for _, flag := range r.BuildFlags {
+		h.Write([]byte{0})
+		h.Write([]byte(flag))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(r.modHash()))
+	h.Write([]byte{0})
+	h.Write([]byte(src))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// modHash 返回 go.mod 与 go.sum 内容的哈希
+func (r *BuildRunner) modHash() string {
+	h := sha256.New()
+	for _, path := range r.ModFiles() {
+		data, _ := os.ReadFile(path)
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// prune 删除超出数量上限的最久未使用的二进制
+func (r *BuildRunner) prune() {
+	if r.MaxBinaries <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Join(r.Dir, binDir))
+	if err != nil || len(entries) <= r.MaxBinaries {
+		return
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, info := range infos[min(r.MaxBinaries, len(infos)):] {
+		if err := os.Remove(filepath.Join(r.Dir, binDir, info.Name())); err != nil {
+			logger.Warnf("删除缓存的二进制失败: %v", err)
+		}
+	}
+}
+
+func (r *BuildRunner) toolchain() string {
+	if r.Toolchain == "" {
+		return "go"
+	}
+	return r.Toolchain
+}
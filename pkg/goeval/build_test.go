@@ -0,0 +1,127 @@
+package goeval
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBuildRunner(t *testing.T) *BuildRunner {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("需要运行 go build")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("找不到 go 命令")
+	}
+	r := NewBuildRunner(t.TempDir())
+	r.Proxy = "off"
+	return r
+}
+
+const helloSrc = "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+
+func TestBuildRunner_Cache(t *testing.T) {
+	r := newTestBuildRunner(t)
+	ctx := context.Background()
+
+	out, err := r.Run(ctx, helloSrc)
+	if err != nil || strings.TrimSpace(out) != "hello" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if r.Timings().Cached {
+		t.Error("first run should build")
+	}
+
+	// 只有格式不同的源码也命中缓存
+	if _, err := r.Run(ctx, strings.ReplaceAll(helloSrc, "\t", "    ")); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if timings := r.Timings(); !timings.Cached || !strings.Contains(timings.String(), "build cached") {
+		t.Errorf("second run should hit the cache: %s", timings)
+	}
+
+	// 编译参数变化后重新编译
+	r.BuildFlags = []string{"-trimpath"}
+	if _, err := r.Run(ctx, helloSrc); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if r.Timings().Cached {
+		t.Error("changing BuildFlags should invalidate the cache")
+	}
+
+	// go.mod 变化后重新编译
+	modPath := filepath.Join(r.Dir, "go.mod")
+	data, err := os.ReadFile(modPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(modPath, append(data, "\n// changed\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(ctx, helloSrc); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if r.Timings().Cached {
+		t.Error("changing go.mod should invalidate the cache")
+	}
+}
+
+func TestBuildRunner_BuildError(t *testing.T) {
+	r := newTestBuildRunner(t)
+	out, err := r.Run(context.Background(), "package main\n\nfunc main() {\n\tundefined()\n}\n")
+	if err == nil || !strings.Contains(out, "undefined") {
+		t.Fatalf("Run() = %q, %v, want build error", out, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(r.Dir, binDir)); len(entries) != 0 {
+		t.Errorf("failed build should not be cached: %v", entries)
+	}
+}
+
+// Test: 超出数量上限时删除最久未使用的二进制
+func TestBuildRunner_Prune(t *testing.T) {
+	r := NewBuildRunner(t.TempDir())
+	r.MaxBinaries = 2
+	dir := filepath.Join(r.Dir, binDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, name := range []string{"old", "mid", "new"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i-3) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.prune()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "mid,new" {
+		t.Errorf("remaining binaries = %v, want [mid new]", names)
+	}
+}
+
+func TestTimingsString(t *testing.T) {
+	timings := Timings{Format: 1500 * time.Microsecond, Build: 2 * time.Second, Run: 30 * time.Millisecond}
+	if got, want := timings.String(), "format 2ms, build 2s, run 30ms"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	timings.Cached = true
+	if got := timings.String(); !strings.Contains(got, "build cached") {
+		t.Errorf("String() = %q, want cached build", got)
+	}
+}
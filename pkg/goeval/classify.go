@@ -0,0 +1,157 @@
+package goeval
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// Kind 输入的类别，决定其在生成源码中的位置
+type Kind int
+
+const (
+	KindImport Kind = iota // import 声明，放在 import 块中
+	KindDecl               // 顶层声明（func、type、var、const），放在 main 之外
+	KindStmt               // 语句，放在 main 中
+	KindExpr               // 单独的表达式，放在 main 中，有值时自动打印
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindImport:
+		return "import"
+	case KindDecl:
+		return "decl"
+	case KindStmt:
+		return "stmt"
+	case KindExpr:
+		return "expr"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Snippet 分类后的输入片段
+type Snippet struct {
+	Kind Kind
+	// Source 片段源码，import 片段为单个 import spec，如 `f "fmt"`
+	Source string
+	// Names 片段声明的名称，用于重复定义时替换旧的声明。
+	// 方法的名称为 `类型.方法`，import 的名称为导入路径
+	Names []string
+}
+
+const (
+	filePrefix = "package main\n"
+	stmtPrefix = "package main\nfunc _() {\n"
+)
+
+// Classify 使用 go/parser 将输入拆分为 import、顶层声明、语句与表达式。
+// 依次尝试按文件、表达式、语句列表解析，都失败时返回按语句解析的错误。
+func Classify(input string) ([]Snippet, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if snippets, ok := classifyFile(input); ok {
+		return snippets, nil
+	}
+	if _, err := parser.ParseExpr(input); err == nil {
+		return []Snippet{{Kind: KindExpr, Source: strings.TrimSpace(input)}}, nil
+	}
+
+	fset := token.NewFileSet()
+	src := stmtPrefix + input + "\n}\n"
+	if _, err := parser.ParseFile(fset, "", src, parser.ParseComments); err != nil {
+		return nil, err
+	}
+	return []Snippet{{Kind: KindStmt, Source: strings.TrimSpace(input)}}, nil
+}
+
+func classifyFile(input string) ([]Snippet, bool) {
+	fset := token.NewFileSet()
+	src := filePrefix + input
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil || len(file.Decls) == 0 {
+		return nil, false
+	}
+	text := func(start, end token.Pos) string {
+		return src[fset.Position(start).Offset:fset.Position(end).Offset]
+	}
+
+	snippets := make([]Snippet, 0, len(file.Decls))
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				for _, spec := range d.Specs {
+					imp := spec.(*ast.ImportSpec)
+					snippets = append(snippets, Snippet{
+						Kind:   KindImport,
+						Source: text(imp.Pos(), imp.End()),
+						Names:  []string{strings.Trim(imp.Path.Value, "`\"")},
+					})
+				}
+				continue
+			}
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			snippets = append(snippets, Snippet{
+				Kind:   KindDecl,
+				Source: text(start, d.End()),
+				Names:  genDeclNames(d),
+			})
+		case *ast.FuncDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			snippets = append(snippets, Snippet{
+				Kind:   KindDecl,
+				Source: text(start, d.End()),
+				Names:  []string{funcDeclName(d)},
+			})
+		}
+	}
+	return snippets, true
+}
+
+func genDeclNames(d *ast.GenDecl) []string {
+	names := make([]string, 0, len(d.Specs))
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				if name.Name != "_" {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func funcDeclName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	return receiverTypeName(d.Recv.List[0].Type) + "." + d.Name.Name
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package goeval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		input string
+		kinds []Kind
+		names [][]string
+	}{
+		{`import "fmt"`, []Kind{KindImport}, [][]string{{"fmt"}}},
+		{`import (f "fmt"; "os")`, []Kind{KindImport, KindImport}, [][]string{{"fmt"}, {"os"}}},
+		{`func add(a, b int) int { return a+b }`, []Kind{KindDecl}, [][]string{{"add"}}},
+		{`func (p *P) String() string { return "" }`, []Kind{KindDecl}, [][]string{{"P.String"}}},
+		{`type P struct{X int}`, []Kind{KindDecl}, [][]string{{"P"}}},
+		{`var a, _, b = 1, 2, 3`, []Kind{KindDecl}, [][]string{{"a", "b"}}},
+		{`x := 1`, []Kind{KindStmt}, [][]string{nil}},
+		{`for i := 0; i < 3; i++ {}`, []Kind{KindStmt}, [][]string{nil}},
+		{`fmt.Println(1)`, []Kind{KindExpr}, [][]string{nil}},
+		{`x + 1`, []Kind{KindExpr}, [][]string{nil}},
+	}
+	for _, tt := range tests {
+		snippets, err := Classify(tt.input)
+		if err != nil {
+			t.Errorf("Classify(%q) error: %v", tt.input, err)
+			continue
+		}
+		kinds := make([]Kind, 0, len(snippets))
+		names := make([][]string, 0, len(snippets))
+		for _, s := range snippets {
+			kinds = append(kinds, s.Kind)
+			names = append(names, s.Names)
+		}
+		if !reflect.DeepEqual(kinds, tt.kinds) || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("Classify(%q) = %v %v, want %v %v", tt.input, kinds, names, tt.kinds, tt.names)
+		}
+	}
+
+	if _, err := Classify("x := "); err == nil {
+		t.Error("Classify() expected syntax error")
+	}
+}
+
+func TestReplaceSnippet(t *testing.T) {
+	decls := []Snippet{
+		{Kind: KindDecl, Source: "func add() {}", Names: []string{"add"}},
+		{Kind: KindDecl, Source: "type P int", Names: []string{"P"}},
+	}
+	decls = replaceSnippet(decls, Snippet{Kind: KindDecl, Source: "func add() int { return 1 }", Names: []string{"add"}})
+	if len(decls) != 2 || decls[0].Names[0] != "P" || decls[1].Source != "func add() int { return 1 }" {
+		t.Errorf("replaceSnippet() = %+v", decls)
+	}
+}
@@ -0,0 +1,199 @@
+package goeval
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+)
+
+// maxFixPasses 修正后可能暴露新的问题（如删除标签后的变量），最多重复修正的次数
+const maxFixPasses = 3
+
+// checkTypes 对单个文件做类型检查，返回检查中的所有错误，错误不会中断检查
+func checkTypes(fset *token.FileSet, file *ast.File, info *types.Info, imp types.Importer) []types.Error {
+	var errs []types.Error
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			if e, ok := err.(types.Error); ok {
+				errs = append(errs, e)
+			}
+		},
+	}
+	conf.Check("main", fset, []*ast.File{file}, info)
+	return errs
+}
+
+// unusedErrorPattern 匹配可以修正的未使用错误，如 `declared and not used: x`、
+// `"fmt" imported and not used`、`"os" imported as f and not used` 与 `label L declared and not used`。
+// `x (variable of type int) is not used` 等表达式语句的错误不在此列
+var unusedErrorPattern = regexp.MustCompile(`^(declared and not used: \S+|"[^"]+" imported( as \S+)? and not used|label \S+ declared and not used)$`)
+
+// textEdit 源码中 [start, end) 范围替换为 text，偏移为字节偏移
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// FixUnused 使用 go/types 在进程内修正会导致编译失败的未使用项：
+//   - 未使用的变量，在声明语句之后补充 `_ = x`
+//   - 未使用的 import，改为 `_` 导入
+//   - 未使用的标签，删除标签
+//
+// 其它错误保持原样，交由编译器报告。只能导入标准库，需要导入模块依赖时使用 NewUnusedFixer。
+func FixUnused(src string) (string, error) {
+	return fixUnused(src, sharedImporter)
+}
+
+// NewUnusedFixer 返回使用 imp 导入依赖的 FixUnused
+func NewUnusedFixer(imp types.Importer) Fixer {
+	return func(src string) (string, error) {
+		return fixUnused(src, imp)
+	}
+}
+
+func fixUnused(src string, imp types.Importer) (string, error) {
+	for i := 0; i < maxFixPasses; i++ {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+		if err != nil {
+			return src, err
+		}
+		errs := checkTypes(fset, file, &types.Info{}, imp)
+
+		edits := make([]textEdit, 0)
+		for _, e := range errs {
+			if !unusedErrorPattern.MatchString(e.Msg) {
+				continue
+			}
+			offset := fset.Position(e.Pos).Offset
+			edits = append(edits, unusedEdits(fset, file, offset)...)
+		}
+		if len(edits) == 0 {
+			return src, nil
+		}
+		src = applyEdits(src, edits)
+	}
+	return src, nil
+}
+
+// unusedEdits 根据错误位置的节点生成修正
+func unusedEdits(fset *token.FileSet, file *ast.File, offset int) []textEdit {
+	pos := fset.File(file.Pos()).Pos(offset)
+	for _, imp := range file.Imports {
+		if imp.Pos() <= pos && pos < imp.End() {
+			start := fset.Position(imp.Pos()).Offset
+			if imp.Name != nil {
+				return []textEdit{{start, fset.Position(imp.Name.End()).Offset, "_"}}
+			}
+			return []textEdit{{start, start, "_ "}}
+		}
+	}
+
+	path := pathTo(file, pos)
+	if len(path) == 0 {
+		return nil
+	}
+	ident, ok := path[len(path)-1].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if labeled, ok := path[len(path)-2].(*ast.LabeledStmt); ok && labeled.Label == ident {
+		return []textEdit{{fset.Position(labeled.Pos()).Offset, fset.Position(labeled.Stmt.Pos()).Offset, ""}}
+	}
+
+	use := "_ = " + ident.Name
+	// 从内向外查找声明变量的语句，在其后或其作用域的开头插入使用
+	for i := len(path) - 2; i >= 0; i-- {
+		switch n := path[i].(type) {
+		case *ast.IfStmt:
+			if isChild(path, i, n.Init) {
+				return []textEdit{insertAfterBrace(fset, n.Body.Lbrace, use)}
+			}
+		case *ast.ForStmt:
+			if isChild(path, i, n.Init) {
+				return []textEdit{insertAfterBrace(fset, n.Body.Lbrace, use)}
+			}
+		case *ast.RangeStmt:
+			return []textEdit{insertAfterBrace(fset, n.Body.Lbrace, use)}
+		case *ast.SwitchStmt:
+			if isChild(path, i, n.Init) {
+				return clauseEdits(fset, n.Body, use)
+			}
+		case *ast.TypeSwitchStmt:
+			if isChild(path, i, n.Init) || isChild(path, i, n.Assign) {
+				return clauseEdits(fset, n.Body, use)
+			}
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			stmt, ok := path[i+1].(ast.Stmt)
+			if !ok {
+				return nil
+			}
+			return []textEdit{{fset.Position(stmt.End()).Offset, fset.Position(stmt.End()).Offset, "; " + use}}
+		case *ast.FuncDecl, *ast.FuncLit:
+			return nil
+		}
+	}
+	return nil
+}
+
+func insertAfterBrace(fset *token.FileSet, lbrace token.Pos, text string) textEdit {
+	offset := fset.Position(lbrace).Offset + 1
+	return textEdit{offset, offset, " " + text + ";"}
+}
+
+// clauseEdits 在 switch 的每个分支开头插入 text
+func clauseEdits(fset *token.FileSet, body *ast.BlockStmt, text string) []textEdit {
+	edits := make([]textEdit, 0, len(body.List))
+	for _, stmt := range body.List {
+		var colon token.Pos
+		switch c := stmt.(type) {
+		case *ast.CaseClause:
+			colon = c.Colon
+		case *ast.CommClause:
+			colon = c.Colon
+		default:
+			continue
+		}
+		offset := fset.Position(colon).Offset + 1
+		edits = append(edits, textEdit{offset, offset, " " + text + ";"})
+	}
+	return edits
+}
+
+// isChild 判断 path[i] 的下一层节点是否为 child
+func isChild(path []ast.Node, i int, child ast.Stmt) bool {
+	return child != nil && i+1 < len(path) && path[i+1] == ast.Node(child)
+}
+
+// pathTo 返回从文件到包含 pos 的最内层节点的路径
+func pathTo(file *ast.File, pos token.Pos) []ast.Node {
+	var path []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || n.Pos() > pos || pos >= n.End() {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+	return path
+}
+
+// applyEdits 从后向前应用修改，起始位置相同的修改按出现顺序插入
+func applyEdits(src string, edits []textEdit) string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	seen := make(map[textEdit]bool)
+	for _, e := range edits {
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		src = src[:e.start] + e.text + src[e.end:]
+	}
+	return src
+}
@@ -0,0 +1,97 @@
+package goeval
+
+import (
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestFixUnused(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "variable",
+			src:  "package main\n\nfunc main() {\n\tx := 1\n}\n",
+			want: "package main\n\nfunc main() {\n\tx := 1; _ = x\n}\n",
+		},
+		{
+			name: "import",
+			src:  "package main\n\nimport (\n\t\"fmt\"\n\tf \"os\"\n)\n\nfunc main() {\n}\n",
+			want: "package main\n\nimport (\n\t_ \"fmt\"\n\t_ \"os\"\n)\n\nfunc main() {\n}\n",
+		},
+		{
+			name: "label",
+			src:  "package main\n\nfunc main() {\nL:\n\tfor {\n\t\tbreak\n\t}\n}\n",
+			want: "package main\n\nfunc main() {\nfor {\n\t\tbreak\n\t}\n}\n",
+		},
+		{
+			name: "if init",
+			src:  "package main\n\nfunc main() {\n\tif x := 1; true {\n\t}\n}\n",
+			want: "package main\n\nfunc main() {\n\tif x := 1; true { _ = x;\n\t}\n}\n",
+		},
+		{
+			name: "used",
+			src:  "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n",
+			want: "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := FixUnused(tt.src)
+		if err != nil {
+			t.Errorf("%s: FixUnused() error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: FixUnused() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "main.go", got, 0)
+		if err != nil {
+			t.Errorf("%s: parse fixed source: %v", tt.name, err)
+			continue
+		}
+		if errs := checkTypes(fset, file, &types.Info{}, sharedImporter); len(errs) > 0 {
+			t.Errorf("%s: fixed source does not compile: %v", tt.name, errs[0])
+		}
+	}
+}
+
+// Test: 表达式语句等其它“not used”错误保持原样，交由编译器报告
+func TestFixUnused_OtherErrors(t *testing.T) {
+	tests := []string{
+		"package main\n\nfunc main() {\n\tx := 1\n\tx\n}\n",
+		"package main\n\nfunc main() {\n\t1 + 2\n}\n",
+		"package main\n\nimport \"strings\"\n\nfunc main() {\n\tstrings.ToUpper\n}\n",
+	}
+	for _, src := range tests {
+		got, err := FixUnused(src)
+		if err != nil {
+			t.Errorf("FixUnused(%q) error: %v", src, err)
+			continue
+		}
+		if got != src {
+			t.Errorf("FixUnused() =\n%s\nwant unchanged\n%s", got, src)
+		}
+	}
+}
+
+func TestUnusedErrorPattern(t *testing.T) {
+	tests := map[string]bool{
+		"declared and not used: x":                   true,
+		`"fmt" imported and not used`:                true,
+		`"os" imported as f and not used`:            true,
+		"label L declared and not used":              true,
+		"x (variable of type int) is not used":       false,
+		"1 + 2 (untyped int constant 3) is not used": false,
+		"result of strings.ToUpper call not used":    false,
+	}
+	for msg, want := range tests {
+		if got := unusedErrorPattern.MatchString(msg); got != want {
+			t.Errorf("match(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
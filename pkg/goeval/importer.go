@@ -0,0 +1,79 @@
+package goeval
+
+import (
+	"context"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// sharedImporter 在所有类型检查之间共享已导入的标准库包，避免重复读取导出数据
+var sharedImporter = &lockedImporter{importer: importer.Default()}
+
+// ImporterRunner 可以为类型检查提供依赖导入的 Runner，
+// 使会话在自动打印与修正未使用项时能识别模块依赖中的包
+type ImporterRunner interface {
+	Runner
+	Importer() types.Importer
+}
+
+type lockedImporter struct {
+	mu       sync.Mutex
+	importer types.Importer
+}
+
+func (i *lockedImporter) Import(path string) (*types.Package, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.importer.Import(path)
+}
+
+// newModuleImporter 在模块目录中通过 `go list -export` 查找导出数据，
+// 可以导入标准库与 go.mod 中的依赖
+func newModuleImporter(toolchain, dir string, env []string) types.Importer {
+	lookup := func(path string) (io.ReadCloser, error) {
+		cmd := exec.Command(toolchain, "list", "-export", "-f", "{{.Export}}", path)
+		cmd.Dir = dir
+		cmd.Env = env
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("查找 %s 的导出数据失败: %w", path, err)
+		}
+		file := strings.TrimSpace(string(out))
+		if file == "" {
+			return nil, fmt.Errorf("%s 没有导出数据", path)
+		}
+		return os.Open(file)
+	}
+	return &lockedImporter{importer: importer.ForCompiler(token.NewFileSet(), "gc", lookup)}
+}
+
+// moduleImporter 依赖变化后重新创建底层的 importer，避免使用旧版本的导出数据
+type moduleImporter struct {
+	mu       sync.Mutex
+	runner   *BuildRunner
+	modHash  string
+	importer types.Importer
+}
+
+func (i *moduleImporter) Import(path string) (*types.Package, error) {
+	i.mu.Lock()
+	// 首次执行前模块目录可能还未初始化
+	if err := i.runner.prepare(context.Background()); err != nil {
+		i.mu.Unlock()
+		return nil, err
+	}
+	if hash := i.runner.modHash(); i.importer == nil || hash != i.modHash {
+		i.modHash = hash
+		i.importer = newModuleImporter(i.runner.toolchain(), i.runner.Dir, i.runner.goEnv())
+	}
+	imp := i.importer
+	i.mu.Unlock()
+	return imp.Import(path)
+}
@@ -0,0 +1,151 @@
+package goeval
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// printable 使用 go/types 判断表达式是否有值，有值的表达式才会被自动打印。
+// 类型无法确定时（如依赖尚未导入的包），函数调用按语句处理，其它表达式照常打印。
+func printable(imp types.Importer, imports, decls []Snippet, stmts []string, expr string) bool {
+	stmts = append(append([]string{}, stmts...), expr)
+	src := render(withMissingImports(imports, decls, stmts), decls, stmts, -1)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		return false
+	}
+	target := lastExpr(file)
+	if target == nil {
+		return false
+	}
+
+	// 未使用的变量等错误不影响类型推断，忽略所有错误
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	checkTypes(fset, file, info, imp)
+
+	tv, ok := info.Types[target]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		_, isCall := target.(*ast.CallExpr)
+		return !isCall
+	}
+	return !tv.IsVoid() && !tv.IsType()
+}
+
+// lastExpr 返回 main 函数中最后一条表达式语句的表达式
+func lastExpr(file *ast.File) ast.Expr {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Name.Name != "main" || fn.Body == nil {
+			continue
+		}
+		list := fn.Body.List
+		if len(list) == 0 {
+			return nil
+		}
+		if stmt, ok := list[len(list)-1].(*ast.ExprStmt); ok {
+			return stmt.X
+		}
+	}
+	return nil
+}
+
+// withMissingImports 返回补充了缺失的标准库导入后的 import 列表
+func withMissingImports(imports, decls []Snippet, stmts []string) []Snippet {
+	missing := missingImports(render(imports, decls, stmts, -1))
+	if len(missing) == 0 {
+		return imports
+	}
+	return append(append([]Snippet{}, imports...), missing...)
+}
+
+// missingImports 查找源码中引用了但未导入的标准库包
+func missingImports(src string) []Snippet {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		return nil
+	}
+	imported := make(map[string]bool)
+	for _, imp := range file.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imported[name] = true
+	}
+
+	var missing []Snippet
+	added := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		// 解析器无法解析的标识符 Obj 为空，包名即属于此类
+		if !ok || ident.Obj != nil || imported[ident.Name] || added[ident.Name] {
+			return true
+		}
+		if p, ok := stdPackages()[ident.Name]; ok {
+			added[ident.Name] = true
+			missing = append(missing, Snippet{Kind: KindImport, Source: strconv.Quote(p), Names: []string{p}})
+		}
+		return true
+	})
+	return missing
+}
+
+// preferredStdPackages 同名标准库包中优先选择的导入路径
+var preferredStdPackages = map[string]string{
+	"rand":     "math/rand",
+	"template": "text/template",
+}
+
+var (
+	stdPackagesOnce sync.Once
+	stdPackagesMap  map[string]string
+)
+
+// stdPackages 返回包名到标准库导入路径的映射，通过 `go list std` 获取一次后缓存。
+// 同名的包优先选择路径较短的。
+func stdPackages() map[string]string {
+	stdPackagesOnce.Do(func() {
+		stdPackagesMap = make(map[string]string)
+		out, err := exec.Command("go", "list", "std").Output()
+		if err != nil {
+			logger.Warnf("获取标准库列表失败: %v", err)
+			return
+		}
+		paths := strings.Fields(string(out))
+		sort.Slice(paths, func(i, j int) bool {
+			if len(paths[i]) != len(paths[j]) {
+				return len(paths[i]) < len(paths[j])
+			}
+			return paths[i] < paths[j]
+		})
+		for _, p := range paths {
+			if strings.Contains(p, "internal") || strings.HasPrefix(p, "vendor/") {
+				continue
+			}
+			name := path.Base(p)
+			if _, ok := stdPackagesMap[name]; !ok {
+				stdPackagesMap[name] = p
+			}
+		}
+		for name, p := range preferredStdPackages {
+			stdPackagesMap[name] = p
+		}
+	})
+	return stdPackagesMap
+}
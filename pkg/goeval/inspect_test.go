@@ -0,0 +1,77 @@
+package goeval
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestPrintable(t *testing.T) {
+	decls := []Snippet{
+		{Kind: KindDecl, Names: []string{"void"}, Source: "func void() {}"},
+		{Kind: KindDecl, Names: []string{"pair"}, Source: "func pair() (int, error) { return 0, nil }"},
+	}
+	stmts := []string{"x := 1"}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"x", true},
+		{"x + 1", true},
+		{`"a"`, true},
+		{"1 << 10", true},
+		{"math.Pi", true},
+		{"pair()", true},
+		{`strconv.Atoi("1")`, true},
+		{"void()", false},
+		{`println("a")`, false},
+		{"int", false},
+		{"[]string", false},
+		{"missing()", false},
+		{"missing", true},
+	}
+	for _, tt := range tests {
+		if got := printable(sharedImporter, nil, decls, stmts, tt.expr); got != tt.want {
+			t.Errorf("printable(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+// Test: 自动打印单值、元组、无类型常量与 error，error 交给 ErrorStyle 渲染
+func TestSessionEval_AutoPrint(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要运行 go run")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("找不到 go 命令")
+	}
+	s := NewSession(
+		WithRunner(NewGoRunRunner(t.TempDir())),
+		WithErrorStyle(func(text string) string { return "<" + text + ">" }),
+	)
+	ctx := context.Background()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x := 2", ""},
+		{"x * 3", "6\n"},
+		{`"a"`, "\"a\"\n"},
+		{"1 << 10", "1024\n"},
+		{"1.5", "1.5\n"},
+		{`strconv.Atoi("12")`, "(12, <nil>)\n"},
+		{`strconv.Atoi("x")`, "(0, <strconv.Atoi: parsing \"x\": invalid syntax>)\n"},
+		{`errors.New("boom")`, "<boom>\n"},
+		{`func f() {}`, ""},
+		{"f()", ""},
+	}
+	for _, tt := range tests {
+		res, err := s.Eval(ctx, tt.input)
+		if err != nil {
+			t.Fatalf("Eval(%q) error: %v\n%s", tt.input, err, res.Output)
+		}
+		if res.Output != tt.want {
+			t.Errorf("Eval(%q) = %q, want %q", tt.input, res.Output, tt.want)
+		}
+	}
+}
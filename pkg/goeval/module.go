@@ -0,0 +1,87 @@
+package goeval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ModFiles 返回模块目录中的 go.mod 与 go.sum 路径，依赖变更后用于通知 LSP
+func (r *BuildRunner) ModFiles() []string {
+	return []string{filepath.Join(r.Dir, "go.mod"), filepath.Join(r.Dir, "go.sum")}
+}
+
+// ModFile 返回模块目录中 go.mod 的内容
+func (r *BuildRunner) ModFile(ctx context.Context) (string, error) {
+	if err := r.prepare(ctx); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(r.Dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Get 通过 go get 添加或升级依赖，如 github.com/x/y@v1.2.3
+func (r *BuildRunner) Get(ctx context.Context, modules ...string) (string, error) {
+	if len(modules) == 0 {
+		return "", fmt.Errorf("缺少模块路径")
+	}
+	return r.GoCommand(ctx, append([]string{"get"}, modules...)...)
+}
+
+// Replace 通过 go mod edit -replace 替换依赖，本地路径会转换为绝对路径
+func (r *BuildRunner) Replace(ctx context.Context, old, new string) (string, error) {
+	if old == "" || new == "" {
+		return "", fmt.Errorf("缺少替换的模块")
+	}
+	if isLocalPath(new) {
+		abs, err := filepath.Abs(new)
+		if err != nil {
+			return "", err
+		}
+		new = abs
+	}
+	return r.GoCommand(ctx, "mod", "edit", "-replace="+old+"="+new)
+}
+
+// DropReplace 移除 go.mod 中的替换
+func (r *BuildRunner) DropReplace(ctx context.Context, old string) (string, error) {
+	if old == "" {
+		return "", fmt.Errorf("缺少替换的模块")
+	}
+	return r.GoCommand(ctx, "mod", "edit", "-dropreplace="+old)
+}
+
+// GoCommand 在模块目录中执行 go 子命令，返回合并后的输出
+func (r *BuildRunner) GoCommand(ctx context.Context, args ...string) (string, error) {
+	if err := r.prepare(ctx); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, r.toolchain(), args...)
+	cmd.Dir = r.Dir
+	cmd.Env = r.goEnv()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("go %s 失败: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// goEnv 执行 go 命令的环境变量：允许自动更新 go.mod，Proxy 非空时覆盖 GOPROXY
+func (r *BuildRunner) goEnv() []string {
+	env := append(os.Environ(), "GOFLAGS=-mod=mod")
+	if r.Proxy != "" {
+		env = append(env, "GOPROXY="+r.Proxy)
+	}
+	return env
+}
+
+func isLocalPath(path string) bool {
+	return path == "." || path == ".." || filepath.IsAbs(path) ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
@@ -0,0 +1,144 @@
+package goeval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newFakeGoRunner 返回使用脚本代替 go 命令的 BuildRunner，脚本回显收到的参数，第一个参数为 fail 时失败
+func newFakeGoRunner(t *testing.T) *BuildRunner {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 /bin/sh")
+	}
+	dir := t.TempDir()
+	toolchain := filepath.Join(t.TempDir(), "go")
+	script := "#!/bin/sh\necho \"$@\"\n[ \"$1\" = fail ] && exit 1\nexit 0\n"
+	if err := os.WriteFile(toolchain, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module goeval\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewBuildRunner(dir)
+	r.Toolchain = toolchain
+	return r
+}
+
+func TestBuildRunner_ModuleCommands(t *testing.T) {
+	r := newFakeGoRunner(t)
+	ctx := context.Background()
+	local, _ := filepath.Abs("./local")
+
+	tests := []struct {
+		name string
+		run  func() (string, error)
+		want string
+	}{
+		{"get", func() (string, error) { return r.Get(ctx, "github.com/x/y@v1.2.3", "github.com/z/w") }, "get github.com/x/y@v1.2.3 github.com/z/w"},
+		{"replace module", func() (string, error) { return r.Replace(ctx, "github.com/x/y", "github.com/x/y2@v1.0.0") }, "mod edit -replace=github.com/x/y=github.com/x/y2@v1.0.0"},
+		{"replace local", func() (string, error) { return r.Replace(ctx, "github.com/x/y", "./local") }, "mod edit -replace=github.com/x/y=" + local},
+		{"drop replace", func() (string, error) { return r.DropReplace(ctx, "github.com/x/y") }, "mod edit -dropreplace=github.com/x/y"},
+	}
+	for _, tt := range tests {
+		out, err := tt.run()
+		if err != nil {
+			t.Errorf("%s: error: %v", tt.name, err)
+			continue
+		}
+		if got := strings.TrimSpace(out); got != tt.want {
+			t.Errorf("%s: args = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRunner_ModuleCommandErrors(t *testing.T) {
+	r := newFakeGoRunner(t)
+	ctx := context.Background()
+
+	if _, err := r.Get(ctx); err == nil {
+		t.Error("Get without modules should fail")
+	}
+	if _, err := r.Replace(ctx, "github.com/x/y", ""); err == nil {
+		t.Error("Replace without target should fail")
+	}
+	if _, err := r.DropReplace(ctx, ""); err == nil {
+		t.Error("DropReplace without module should fail")
+	}
+
+	out, err := r.GoCommand(ctx, "fail", "now")
+	if err == nil || !strings.Contains(err.Error(), "go fail now 失败") {
+		t.Errorf("GoCommand error = %v", err)
+	}
+	if strings.TrimSpace(out) != "fail now" {
+		t.Errorf("GoCommand should return the output on failure, got %q", out)
+	}
+}
+
+func TestBuildRunner_GoEnv(t *testing.T) {
+	r := NewBuildRunner(t.TempDir())
+	r.Proxy = "off"
+	env := r.goEnv()
+	if !slices.Contains(env, "GOFLAGS=-mod=mod") || env[len(env)-1] != "GOPROXY=off" {
+		t.Errorf("goEnv() missing GOFLAGS or GOPROXY")
+	}
+}
+
+func TestIsLocalPath(t *testing.T) {
+	tests := map[string]bool{
+		".":                true,
+		"..":               true,
+		"./x":              true,
+		"../x":             true,
+		"github.com/x/y":   false,
+		"example.com/x@v1": false,
+	}
+	if runtime.GOOS != "windows" {
+		tests["/abs/path"] = true
+	}
+	for path, want := range tests {
+		if got := isLocalPath(path); got != want {
+			t.Errorf("isLocalPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// Test: 模块导入器可以导入标准库，go.mod 变化后重新创建底层的 importer
+func TestModuleImporter(t *testing.T) {
+	r := newTestBuildRunner(t)
+	imp := r.Importer().(*moduleImporter)
+
+	pkg, err := imp.Import("strings")
+	if err != nil {
+		t.Fatalf("Import(strings) error: %v", err)
+	}
+	if pkg.Scope().Lookup("ToUpper") == nil {
+		t.Error("strings.ToUpper not found")
+	}
+	first := imp.importer
+
+	if _, err := imp.Import("strings"); err != nil || imp.importer != first {
+		t.Errorf("unchanged go.mod should reuse the importer (err %v)", err)
+	}
+
+	modPath := filepath.Join(r.Dir, "go.mod")
+	data, _ := os.ReadFile(modPath)
+	if err := os.WriteFile(modPath, append(data, "\n// changed\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imp.Import("strings"); err != nil {
+		t.Fatalf("Import after go.mod change error: %v", err)
+	}
+	if imp.importer == first {
+		t.Error("go.mod change should recreate the importer")
+	}
+
+	if _, err := imp.Import("example.com/does/not/exist"); err == nil {
+		t.Error("importing a missing package should fail")
+	}
+}
@@ -0,0 +1,59 @@
+package goeval
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Runner 编译并运行完整的 Go 源码，返回合并后的标准输出与标准错误
+type Runner interface {
+	Run(ctx context.Context, src string) (string, error)
+}
+
+// GoRunRunner 将源码写入目录后使用 goimports 补全 import，再通过 go run 执行
+type GoRunRunner struct {
+	// Dir 源码所在目录，为空时使用临时目录
+	Dir string
+}
+
+func NewGoRunRunner(dir string) *GoRunRunner {
+	return &GoRunRunner{Dir: dir}
+}
+
+func (r *GoRunRunner) Run(ctx context.Context, src string) (string, error) {
+	if r.Dir == "" {
+		dir, err := os.MkdirTemp("", "goeval-")
+		if err != nil {
+			return "", fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		r.Dir = dir
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return "", err
+	}
+	codePath := filepath.Join(r.Dir, "main.go")
+	if err := os.WriteFile(codePath, []byte(src), 0o644); err != nil {
+		return "", fmt.Errorf("写入源码失败: %w", err)
+	}
+
+	if goimports, err := exec.LookPath("goimports"); err == nil {
+		if out, err := exec.CommandContext(ctx, goimports, "-w", codePath).CombinedOutput(); err != nil {
+			return string(out), fmt.Errorf("goimports 失败: %w", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "run", codePath)
+	cmd.Dir = r.Dir
+	var out bytes.Buffer
+	// 标准输出与标准错误写入同一个缓冲区，保证输出顺序
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("go run 失败: %w", err)
+	}
+	return out.String(), nil
+}
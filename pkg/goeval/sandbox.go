@@ -0,0 +1,143 @@
+package goeval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const runDir = "run"
+
+// TerminationReason 程序被终止的原因
+type TerminationReason string
+
+const (
+	ReasonTimeout     TerminationReason = "timeout"
+	ReasonCPULimit    TerminationReason = "cpu limit"
+	ReasonMemoryLimit TerminationReason = "memory limit"
+	ReasonSignal      TerminationReason = "signal"
+)
+
+// TerminationError 程序因超时、信号或资源限制被终止时返回的错误，Reason 说明终止原因。
+// 程序自行以非零状态退出（包括 panic）时返回 *exec.ExitError
+type TerminationError struct {
+	Reason TerminationReason
+	// Detail 补充说明，如超时时长、信号名称或资源上限
+	Detail string
+	Err    error
+}
+
+func (e *TerminationError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("程序被终止: %s", e.Reason)
+	}
+	return fmt.Sprintf("程序被终止: %s (%s)", e.Reason, e.Detail)
+}
+
+func (e *TerminationError) Unwrap() error {
+	return e.Err
+}
+
+// Sandbox 限制被执行程序的资源与权限，零值字段表示不限制。
+// CPU、内存限制与进程组仅在类 Unix 平台生效。
+type Sandbox struct {
+	// Timeout 运行的最长时间，超时后结束整个进程组
+	Timeout time.Duration
+	// CPUTime CPU 时间上限（RLIMIT_CPU），精度为秒
+	CPUTime time.Duration
+	// Memory 数据段内存上限（RLIMIT_DATA），单位为字节
+	Memory uint64
+	// ReadOnlyDir 在只读的空目录中运行程序，以 root 运行时目录权限不生效
+	ReadOnlyDir bool
+	// Env 允许传递给程序的环境变量名，以 * 结尾时按前缀匹配，nil 表示继承全部环境变量
+	Env []string
+}
+
+// DefaultSandbox 适合交互式执行的默认限制
+func DefaultSandbox() *Sandbox {
+	return &Sandbox{
+		Timeout: 10 * time.Second,
+		CPUTime: 10 * time.Second,
+		Memory:  1 << 30,
+		Env:     []string{"PATH", "HOME", "USER", "LANG", "LC_*", "TERM", "TZ", "TMPDIR"},
+	}
+}
+
+// command 生成在沙箱中运行 bin 的命令，返回的 context 需要在命令结束后取消
+func (s *Sandbox) command(ctx context.Context, bin, dir string) (*exec.Cmd, context.Context, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if s.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+	}
+
+	cmd := limitCommand(ctx, bin, s.CPUTime, s.Memory)
+	cmd.Dir = dir
+	if s.ReadOnlyDir {
+		roDir, err := readOnlyDir(dir)
+		if err != nil {
+			cancel()
+			return nil, nil, nil, err
+		}
+		cmd.Dir = roDir
+	}
+	if s.Env != nil {
+		cmd.Env = filterEnv(os.Environ(), s.Env)
+	}
+	setProcessGroup(cmd)
+	// 子进程可能继承了输出管道，进程组被结束后不再无限等待
+	cmd.WaitDelay = time.Second
+	return cmd, ctx, cancel, nil
+}
+
+// termination 将超时、信号与资源限制导致的运行错误转换为 TerminationError，其他错误原样返回
+func (s *Sandbox) termination(ctx context.Context, err error, output string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &TerminationError{Reason: ReasonTimeout, Detail: s.Timeout.String(), Err: err}
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if reason, detail, ok := signalReason(exitErr.ProcessState, s.CPUTime); ok {
+		return &TerminationError{Reason: reason, Detail: detail, Err: err}
+	}
+	if s.Memory > 0 && (strings.Contains(output, "out of memory") || strings.Contains(output, "cannot allocate memory")) {
+		return &TerminationError{Reason: ReasonMemoryLimit, Detail: fmt.Sprintf("%d MiB", s.Memory>>20), Err: err}
+	}
+	return err
+}
+
+// readOnlyDir 在 dir 下创建只读的空目录
+func readOnlyDir(dir string) (string, error) {
+	path := filepath.Join(dir, runDir)
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(path, 0o555); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// filterEnv 只保留 allow 中列出的环境变量
+func filterEnv(environ, allow []string) []string {
+	env := make([]string, 0, len(allow))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, pattern := range allow {
+			if name == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))) {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
@@ -0,0 +1,38 @@
+package goeval
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestFilterEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", "HOME=/root", "LC_ALL=C", "LC_CTYPE=UTF-8", "SECRET=x"}
+	got := filterEnv(environ, []string{"PATH", "LC_*"})
+	want := []string{"PATH=/bin", "LC_ALL=C", "LC_CTYPE=UTF-8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterEnv() = %v, want %v", got, want)
+	}
+}
+
+// Test: 程序自行以非零状态退出时返回退出错误而不是 TerminationError
+func TestSandbox_ExitIsNotTermination(t *testing.T) {
+	r := newTestBuildRunner(t)
+	r.Sandbox = DefaultSandbox()
+	for _, src := range []string{
+		"package main\n\nimport \"os\"\n\nfunc main() {\n\tos.Exit(3)\n}\n",
+		"package main\n\nfunc main() {\n\tpanic(\"boom\")\n}\n",
+	} {
+		out, err := r.Run(context.Background(), src)
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("Run() error = %v, want *exec.ExitError\n%s", err, out)
+		}
+		var termErr *TerminationError
+		if errors.As(err, &termErr) {
+			t.Errorf("Run() error = %v, want plain exit error", err)
+		}
+	}
+}
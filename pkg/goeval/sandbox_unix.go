@@ -0,0 +1,55 @@
+//go:build !windows
+
+package goeval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// limitCommand 通过 sh 的 ulimit 为程序设置 CPU 与内存上限
+func limitCommand(ctx context.Context, bin string, cpu time.Duration, memory uint64) *exec.Cmd {
+	if cpu <= 0 && memory == 0 {
+		return exec.CommandContext(ctx, bin)
+	}
+	script := ""
+	if cpu > 0 {
+		script += fmt.Sprintf("ulimit -t %d && ", max(int64(cpu/time.Second), 1))
+	}
+	if memory > 0 {
+		script += fmt.Sprintf("ulimit -d %d && ", max(memory>>10, 1))
+	}
+	script += `exec "$0"`
+	return exec.CommandContext(ctx, "/bin/sh", "-c", script, bin)
+}
+
+// setProcessGroup 让程序在独立的进程组中运行，结束时连同其子进程一起结束
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// signalReason 判断程序是否被信号结束。
+// Go 程序不会因 SIGXCPU 退出，达到 CPU 硬上限后由内核发送 SIGKILL，此时按 CPU 用时判断，
+// 内核按时钟节拍统计用时，允许略低于上限。
+func signalReason(state *os.ProcessState, cpu time.Duration) (TerminationReason, string, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return "", "", false
+	}
+	sig := status.Signal()
+	used := state.UserTime() + state.SystemTime()
+	if sig == syscall.SIGXCPU || (sig == syscall.SIGKILL && cpu > 0 && used >= cpu*9/10) {
+		return ReasonCPULimit, cpu.String(), true
+	}
+	return ReasonSignal, sig.String(), true
+}
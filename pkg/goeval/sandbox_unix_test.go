@@ -0,0 +1,80 @@
+//go:build !windows
+
+package goeval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// spinSrc 启动一个继承输出的子进程后进入死循环
+const spinSrc = `package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func main() {
+	cmd := exec.Command("sleep", "60")
+	cmd.Stdout = os.Stdout
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+	fmt.Println(cmd.Process.Pid)
+	for {
+	}
+}
+`
+
+// Test: 超时后结束整个进程组，继承了输出管道的子进程不会阻塞返回
+func TestSandbox_TimeoutKillsProcessGroup(t *testing.T) {
+	r := newTestBuildRunner(t)
+	r.Sandbox = &Sandbox{Timeout: time.Second}
+
+	start := time.Now()
+	out, err := r.Run(context.Background(), spinSrc)
+	elapsed := time.Since(start) - r.Timings().Build - r.Timings().Format
+
+	var termErr *TerminationError
+	if !errors.As(err, &termErr) || termErr.Reason != ReasonTimeout {
+		t.Fatalf("Run() error = %v, want timeout\n%s", err, out)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Run() took %s after build, want about Timeout", elapsed)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		t.Fatalf("unexpected output %q", out)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d is still running", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// processAlive 判断进程是否仍在运行，僵尸进程视为已结束
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	// 格式为 pid (comm) state ...
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package goeval
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// limitCommand Windows 平台不支持 CPU 与内存上限
+func limitCommand(ctx context.Context, bin string, cpu time.Duration, memory uint64) *exec.Cmd {
+	if cpu > 0 || memory > 0 {
+		logger.Warnf("当前平台不支持 CPU 与内存限制")
+	}
+	return exec.CommandContext(ctx, bin)
+}
+
+// setProcessGroup Windows 平台没有进程组，超时时只结束程序本身
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalReason(state *os.ProcessState, cpu time.Duration) (TerminationReason, string, bool) {
+	return "", "", false
+}
@@ -0,0 +1,411 @@
+package goeval
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wxnacy/code-prompt/pkg/log"
+)
+
+var logger = log.GetLogger()
+
+// outputMarker 插入在新输入之前，用于从重放的完整输出中截取本次新增的输出
+const outputMarker = "\x1e__goeval_output__\x1e"
+
+const (
+	// errorStart 与 errorEnd 包裹自动打印的 error 值，输出时交给 ErrorStyle 渲染
+	errorStart = "\x1e__goeval_error__"
+	errorEnd   = "\x1f"
+
+	printFunc   = "__goevalPrint"
+	printImport = "__goevalfmt"
+)
+
+// printDecl 自动打印表达式的辅助函数，单个值使用 %#v 打印，
+// 多个返回值打印为元组，error 值打印其 Error() 并加上标记
+var printDecl = Snippet{
+	Kind:  KindDecl,
+	Names: []string{printFunc},
+	Source: `func ` + printFunc + `(values ...any) {
+	out := ""
+	for i, v := range values {
+		if i > 0 {
+			out += ", "
+		}
+		if err, ok := v.(error); ok {
+			out += ` + strconv.Quote(errorStart) + ` + err.Error() + ` + strconv.Quote(errorEnd) + `
+		} else {
+			out += ` + printImport + `.Sprintf("%#v", v)
+		}
+	}
+	if len(values) > 1 {
+		out = "(" + out + ")"
+	}
+	` + printImport + `.Println(out)
+}`,
+}
+
+type (
+	// Fixer 在执行前修正生成的源码，如为未使用的变量补充 `_ = x`
+	Fixer func(src string) (string, error)
+
+	Option func(*Session)
+
+	// ErrorStyle 渲染自动打印的 error 值
+	ErrorStyle func(text string) string
+)
+
+// Result 单次执行的结果
+type Result struct {
+	Input  string // 本次输入
+	Output string // 本次输入新增的输出
+	Source string // 实际执行的完整源码
+	// Timings 各阶段耗时，仅在 Runner 实现了 TimingRunner 时有值
+	Timings Timings
+}
+
+// Session 累积状态的 Go REPL 会话。
+//
+// 每次执行都会把历史语句与新输入重新生成为完整的程序并运行（重放），
+// 新输入之前会打印输出标记，只有标记之后的输出才会返回，
+// 标记缺失时（如程序提前退出）退回到与上一次输出做前缀比较。
+// 输入按 Classify 的结果分别放入 import 块、顶层声明与 main 中，
+// 同名的声明会替换之前的定义，有值的表达式会自动打印。
+// 只有编译与运行都成功的输入才会被记入会话。
+type Session struct {
+	mu sync.Mutex
+
+	imports []Snippet
+	decls   []Snippet
+	stmts   []string
+
+	lastOutput string
+
+	runner     Runner
+	fixer      Fixer
+	errorStyle ErrorStyle
+	// importer 类型检查时导入依赖，Runner 实现了 ImporterRunner 时使用其模块中的依赖
+	importer types.Importer
+}
+
+func NewSession(opts ...Option) *Session {
+	s := &Session{
+		imports: make([]Snippet, 0),
+		decls:   make([]Snippet, 0),
+		stmts:   make([]string, 0),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.runner == nil {
+		s.runner = NewGoRunRunner("")
+	}
+	s.importer = sharedImporter
+	if ir, ok := s.runner.(ImporterRunner); ok {
+		s.importer = ir.Importer()
+	}
+	if s.fixer == nil {
+		s.fixer = NewUnusedFixer(s.importer)
+	}
+	return s
+}
+
+// WithRunner 设置执行后端
+func WithRunner(r Runner) Option {
+	return func(s *Session) {
+		s.runner = r
+	}
+}
+
+// WithFixer 设置执行前的源码修正方法，默认修正未使用的变量、import 与标签
+func WithFixer(f Fixer) Option {
+	return func(s *Session) {
+		s.fixer = f
+	}
+}
+
+// WithErrorStyle 设置自动打印的 error 值的渲染方法，默认原样输出
+func WithErrorStyle(style ErrorStyle) Option {
+	return func(s *Session) {
+		s.errorStyle = style
+	}
+}
+
+// Eval 执行输入，成功时将其记入会话并返回新增的输出。
+// 失败时 Result 中仍包含编译或运行的输出，会话状态保持不变。
+func (s *Session) Eval(ctx context.Context, input string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &Result{Input: input}
+	if strings.TrimSpace(input) == "" {
+		return result, nil
+	}
+
+	snippets, err := Classify(input)
+	if err != nil {
+		return result, err
+	}
+	imports := append([]Snippet{}, s.imports...)
+	decls := append([]Snippet{}, s.decls...)
+	stmts := append([]string{}, s.stmts...)
+	for _, snippet := range snippets {
+		switch snippet.Kind {
+		case KindImport:
+			imports = replaceSnippet(imports, snippet)
+		case KindDecl:
+			decls = replaceSnippet(decls, snippet)
+		case KindExpr:
+			if printable(s.importer, imports, decls, stmts, snippet.Source) {
+				stmts = append(stmts, printFunc+"("+snippet.Source+")")
+			} else {
+				stmts = append(stmts, snippet.Source)
+			}
+		default:
+			stmts = append(stmts, redeclare(stmts, snippet.Source))
+		}
+	}
+
+	src, err := s.build(imports, decls, stmts, len(s.stmts))
+	if err != nil {
+		return result, err
+	}
+	result.Source = src
+
+	output, err := s.runner.Run(ctx, src)
+	if tr, ok := s.runner.(TimingRunner); ok {
+		result.Timings = tr.Timings()
+	}
+	result.Output = s.newOutput(output)
+	if err != nil {
+		return result, err
+	}
+
+	s.imports = imports
+	s.decls = decls
+	s.stmts = stmts
+	s.lastOutput = stripMarker(output)
+	return result, nil
+}
+
+// Source 返回当前会话对应的完整源码
+func (s *Session) Source() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.build(s.imports, s.decls, s.stmts, -1)
+}
+
+// Reset 清空会话状态
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.imports = s.imports[:0]
+	s.decls = s.decls[:0]
+	s.stmts = s.stmts[:0]
+	s.lastOutput = ""
+}
+
+// build 生成完整源码，markerAt 为输出标记插入的语句下标，小于 0 表示不插入
+func (s *Session) build(imports, decls []Snippet, stmts []string, markerAt int) (string, error) {
+	src := render(withMissingImports(imports, decls, stmts), decls, stmts, markerAt)
+	if s.fixer == nil {
+		return src, nil
+	}
+	fixed, err := s.fixer(src)
+	if err != nil {
+		logger.Warnf("修正源码失败: %v", err)
+		return src, nil
+	}
+	return fixed, nil
+}
+
+// newOutput 从完整输出中截取本次新增的部分
+func (s *Session) newOutput(output string) string {
+	if idx := strings.LastIndex(output, outputMarker); idx >= 0 {
+		return s.styleErrors(output[idx+len(outputMarker):])
+	}
+	if strings.HasPrefix(output, s.lastOutput) {
+		return s.styleErrors(output[len(s.lastOutput):])
+	}
+	return s.styleErrors(output)
+}
+
+// styleErrors 去掉 error 值的标记，并使用 errorStyle 渲染
+func (s *Session) styleErrors(output string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(output, errorStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(output[start:], errorEnd)
+		if end < 0 {
+			break
+		}
+		text := output[start+len(errorStart) : start+end]
+		if s.errorStyle != nil {
+			text = s.errorStyle(text)
+		}
+		b.WriteString(output[:start])
+		b.WriteString(text)
+		output = output[start+end+len(errorEnd):]
+	}
+	b.WriteString(output)
+	return b.String()
+}
+
+func stripMarker(output string) string {
+	return strings.ReplaceAll(output, outputMarker, "")
+}
+
+// redeclare 将重复声明已有变量的 `:=` 改写为 `=`，如先后输入 `x := 1` 与 `x := 2`，
+// 避免重放时编译失败（no new variables on left side of :=）
+func redeclare(stmts []string, stmt string) string {
+	declared := declaredNames(stmts)
+	if len(declared) == 0 {
+		return stmt
+	}
+	fset := token.NewFileSet()
+	src := stmtPrefix + stmt + "\n}\n"
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return stmt
+	}
+	body := file.Decls[0].(*ast.FuncDecl).Body
+	offsets := make([]int, 0)
+	for _, st := range body.List {
+		assign, ok := st.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE || !allDeclared(assign.Lhs, declared) {
+			continue
+		}
+		offsets = append(offsets, fset.Position(assign.TokPos).Offset-len(stmtPrefix))
+	}
+	// 从后向前替换，保证前面的偏移有效
+	for i := len(offsets) - 1; i >= 0; i-- {
+		stmt = stmt[:offsets[i]] + "=" + stmt[offsets[i]+len(":="):]
+	}
+	return stmt
+}
+
+// declaredNames 返回语句列表在 main 函数顶层声明的变量名
+func declaredNames(stmts []string) map[string]bool {
+	fset := token.NewFileSet()
+	src := stmtPrefix + strings.Join(stmts, "\n") + "\n}\n"
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, st := range file.Decls[0].(*ast.FuncDecl).Body.List {
+		switch st := st.(type) {
+		case *ast.AssignStmt:
+			if st.Tok != token.DEFINE {
+				continue
+			}
+			for _, lhs := range st.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					names[ident.Name] = true
+				}
+			}
+		case *ast.DeclStmt:
+			if gen, ok := st.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+				for _, spec := range gen.Specs {
+					for _, ident := range spec.(*ast.ValueSpec).Names {
+						names[ident.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// allDeclared 判断左侧的变量是否都已声明，`_` 视为已声明
+func allDeclared(lhs []ast.Expr, declared map[string]bool) bool {
+	for _, expr := range lhs {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		if ident.Name != "_" && !declared[ident.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceSnippet 移除与 snippet 声明了相同名称的旧片段后追加 snippet
+func replaceSnippet(snippets []Snippet, snippet Snippet) []Snippet {
+	result := snippets[:0]
+	for _, old := range snippets {
+		if !sharesName(old, snippet) {
+			result = append(result, old)
+		}
+	}
+	return append(result, snippet)
+}
+
+func sharesName(a, b Snippet) bool {
+	for _, x := range a.Names {
+		for _, y := range b.Names {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesPrinter 判断语句中是否有自动打印的表达式
+func usesPrinter(stmts []string) bool {
+	for _, stmt := range stmts {
+		if strings.HasPrefix(stmt, printFunc+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+func render(imports, decls []Snippet, stmts []string, markerAt int) string {
+	if usesPrinter(stmts) {
+		imports = append(append([]Snippet{}, imports...), Snippet{Kind: KindImport, Source: printImport + ` "fmt"`})
+		decls = append(append([]Snippet{}, decls...), printDecl)
+	}
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	if len(imports) > 0 {
+		b.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&b, "\t%s\n", imp.Source)
+		}
+		b.WriteString(")\n\n")
+	}
+	for _, decl := range decls {
+		b.WriteString(decl.Source)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("func main() {\n")
+	for i, stmt := range stmts {
+		if i == markerAt {
+			writeMarker(&b)
+		}
+		fmt.Fprintf(&b, "\t%s\n", stmt)
+	}
+	if markerAt == len(stmts) {
+		// 本次输入只有声明，新增输出为空
+		writeMarker(&b)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func writeMarker(b *strings.Builder) {
+	fmt.Fprintf(b, "\tprint(%q)\n", outputMarker)
+}
@@ -0,0 +1,92 @@
+package goeval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeRunner 按语句顺序回显 print 调用的参数，用于模拟重放执行
+type fakeRunner struct {
+	fail string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, src string) (string, error) {
+	if r.fail != "" && strings.Contains(src, r.fail) {
+		return "compile error", errors.New("exit status 1")
+	}
+	var out strings.Builder
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "print(") {
+			arg := strings.TrimSuffix(strings.TrimPrefix(line, "print("), ")")
+			if strings.Contains(arg, "__goeval_output__") {
+				out.WriteString(outputMarker)
+				continue
+			}
+			out.WriteString(strings.Trim(arg, `"`))
+		}
+	}
+	return out.String(), nil
+}
+
+func TestSessionEval(t *testing.T) {
+	runner := &fakeRunner{fail: "bad"}
+	s := NewSession(WithRunner(runner))
+	ctx := context.Background()
+
+	res, err := s.Eval(ctx, `print("a")`)
+	if err != nil || res.Output != "a" {
+		t.Fatalf("Eval() = %q, %v", res.Output, err)
+	}
+	res, err = s.Eval(ctx, `print("b")`)
+	if err != nil || res.Output != "b" {
+		t.Fatalf("Eval() = %q, %v, want only new output", res.Output, err)
+	}
+
+	if _, err = s.Eval(ctx, "bad"); err == nil {
+		t.Fatal("Eval() expected error")
+	}
+	src, _ := s.Source()
+	if strings.Contains(src, "bad") {
+		t.Errorf("failed input should not be committed:\n%s", src)
+	}
+}
+
+func TestRedeclare(t *testing.T) {
+	stmts := []string{"x := 1", "var y int", "for i := 0; i < 1; i++ {}"}
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"x := 2", "x = 2"},
+		{"x, y := 3, 4", "x, y = 3, 4"},
+		{"_, x := 3, 4", "_, x = 3, 4"},
+		{"x, z := 3, 4", "x, z := 3, 4"},
+		{"i := 0", "i := 0"},
+		{"x := 1; x := 2", "x = 1; x = 2"},
+		{"if x := 2; x > 0 {}", "if x := 2; x > 0 {}"},
+		{"s := `:=`; x := 2", "s := `:=`; x = 2"},
+	}
+	for _, tt := range tests {
+		if got := redeclare(stmts, tt.stmt); got != tt.want {
+			t.Errorf("redeclare(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+// Test: 重复的 `x := 1` 与 `x := 2` 重放时不会因 no new variables 编译失败
+func TestSessionEval_Redeclare(t *testing.T) {
+	s := NewSession(WithRunner(&fakeRunner{fail: "x := 2"}))
+	ctx := context.Background()
+	for _, input := range []string{"x := 1", "x := 2"} {
+		if _, err := s.Eval(ctx, input); err != nil {
+			t.Fatalf("Eval(%q) error: %v", input, err)
+		}
+	}
+	src, _ := s.Source()
+	if !strings.Contains(src, "x := 1") || !strings.Contains(src, "x = 2") {
+		t.Errorf("source should keep the first declaration and assign the second:\n%s", src)
+	}
+}
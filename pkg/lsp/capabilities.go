@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LSP 方法名，同时作为功能开关的标识
+const (
+	MethodCompletion    = "textDocument/completion"
+	MethodHover         = "textDocument/hover"
+	MethodSignatureHelp = "textDocument/signatureHelp"
+)
+
+// ErrUnsupported 服务端未声明支持某项功能时返回，可配合 errors.Is 判断
+var ErrUnsupported = errors.New("lsp: 服务端不支持该功能")
+
+// UnsupportedError 记录具体不被支持的 LSP 方法
+type UnsupportedError struct {
+	Method string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("LSP 服务端不支持 %s", e.Method)
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	ResolveProvider   bool     `json:"resolveProvider,omitempty"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters   []string `json:"triggerCharacters,omitempty"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+// ServerCapabilities 服务端在 initialize 响应中声明的能力
+// 部分字段在协议中既可以是 bool 也可以是对象，使用 json.RawMessage 保留原始值
+type ServerCapabilities struct {
+	TextDocumentSync      json.RawMessage       `json:"textDocumentSync,omitempty"`
+	CompletionProvider    *CompletionOptions    `json:"completionProvider,omitempty"`
+	HoverProvider         json.RawMessage       `json:"hoverProvider,omitempty"`
+	SignatureHelpProvider *SignatureHelpOptions `json:"signatureHelpProvider,omitempty"`
+
+	SemanticTokensProvider *SemanticTokensOptions `json:"semanticTokensProvider,omitempty"`
+
+	CodeActionProvider     json.RawMessage        `json:"codeActionProvider,omitempty"`
+	ExecuteCommandProvider *ExecuteCommandOptions `json:"executeCommandProvider,omitempty"`
+
+	DocumentFormattingProvider      json.RawMessage `json:"documentFormattingProvider,omitempty"`
+	DocumentRangeFormattingProvider json.RawMessage `json:"documentRangeFormattingProvider,omitempty"`
+
+	Workspace *WorkspaceServerCapabilities `json:"workspace,omitempty"`
+}
+
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   *ServerInfo        `json:"serverInfo,omitempty"`
+}
+
+// Supports 判断服务端是否支持指定的 LSP 方法
+func (s ServerCapabilities) Supports(method string) bool {
+	switch method {
+	case MethodCompletion:
+		return s.CompletionProvider != nil
+	case MethodHover:
+		return providerEnabled(s.HoverProvider)
+	case MethodSignatureHelp:
+		return s.SignatureHelpProvider != nil
+	case MethodSemanticTokensFull:
+		return s.SemanticTokensProvider != nil && providerEnabled(s.SemanticTokensProvider.Full)
+	case MethodCodeAction:
+		return providerEnabled(s.CodeActionProvider)
+	case MethodFormatting:
+		return providerEnabled(s.DocumentFormattingProvider)
+	case MethodRangeFormatting:
+		return providerEnabled(s.DocumentRangeFormattingProvider)
+	case MethodExecuteCommand:
+		return s.ExecuteCommandProvider != nil
+	case MethodDidChangeWorkspaceFolders:
+		return s.Workspace != nil && s.Workspace.WorkspaceFolders != nil && s.Workspace.WorkspaceFolders.Supported
+	case MethodDidChangeWatchedFiles:
+		// 由客户端声明能力，服务端通过动态注册订阅，这里总是允许发送
+		return true
+	default:
+		return false
+	}
+}
+
+// providerEnabled 解析 `boolean | Options` 形式的能力声明
+func providerEnabled(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) || bytes.Equal(raw, []byte("false")) {
+		return false
+	}
+	return true
+}
+
+// clientCapabilities 返回客户端实际支持的能力，随 initialize 请求发送
+func clientCapabilities() map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"synchronization": map[string]interface{}{
+				"dynamicRegistration": false,
+				"didSave":             false,
+			},
+			"completion": map[string]interface{}{
+				"dynamicRegistration": false,
+				"completionItem": map[string]interface{}{
+					"snippetSupport":          false,
+					"documentationFormat":     []string{"plaintext", "markdown"},
+					"deprecatedSupport":       true,
+					"insertReplaceSupport":    false,
+					"labelDetailsSupport":     false,
+					"commitCharactersSupport": false,
+				},
+				"contextSupport": false,
+			},
+			"hover": map[string]interface{}{
+				"dynamicRegistration": false,
+				"contentFormat":       []string{"plaintext", "markdown"},
+			},
+			"signatureHelp": map[string]interface{}{
+				"dynamicRegistration": false,
+				"signatureInformation": map[string]interface{}{
+					"documentationFormat": []string{"plaintext", "markdown"},
+					"parameterInformation": map[string]interface{}{
+						"labelOffsetSupport": true,
+					},
+				},
+			},
+			"publishDiagnostics": map[string]interface{}{
+				"relatedInformation": false,
+				"versionSupport":     true,
+			},
+			"codeAction": map[string]interface{}{
+				"dynamicRegistration": false,
+				"codeActionLiteralSupport": map[string]interface{}{
+					"codeActionKind": map[string]interface{}{
+						"valueSet": []string{
+							"", CodeActionQuickFix, CodeActionRefactor, "refactor.extract", "refactor.inline",
+							"refactor.rewrite", CodeActionSource, CodeActionSourceOrganizeImports, CodeActionSourceFixAll,
+						},
+					},
+				},
+				"isPreferredSupport": true,
+				"dataSupport":        true,
+				"resolveSupport": map[string]interface{}{
+					"properties": []string{"edit"},
+				},
+			},
+			"formatting": map[string]interface{}{
+				"dynamicRegistration": false,
+			},
+			"rangeFormatting": map[string]interface{}{
+				"dynamicRegistration": false,
+			},
+			"semanticTokens": map[string]interface{}{
+				"dynamicRegistration": false,
+				"requests": map[string]interface{}{
+					"range": false,
+					"full":  true,
+				},
+				"tokenTypes":              semanticTokenTypes,
+				"tokenModifiers":          semanticTokenModifiers,
+				"formats":                 []string{"relative"},
+				"overlappingTokenSupport": false,
+				"multilineTokenSupport":   false,
+			},
+		},
+		"workspace": map[string]interface{}{
+			"applyEdit": true,
+			"workspaceEdit": map[string]interface{}{
+				"documentChanges": true,
+			},
+			"executeCommand": map[string]interface{}{
+				"dynamicRegistration": false,
+			},
+			"workspaceFolders": true,
+			"configuration":    true,
+			"didChangeWatchedFiles": map[string]interface{}{
+				"dynamicRegistration": true,
+			},
+		},
+		"window": map[string]interface{}{
+			"workDoneProgress": false,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestProviderEnabled(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{"", false},
+		{"null", false},
+		{"false", false},
+		{" false ", false},
+		{"true", true},
+		{"{}", true},
+		{`{"workDoneProgress":true}`, true},
+	}
+	for _, tt := range tests {
+		if got := providerEnabled(json.RawMessage(tt.raw)); got != tt.want {
+			t.Errorf("providerEnabled(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestServerCapabilities_Supports(t *testing.T) {
+	var caps ServerCapabilities
+	raw := `{
+		"hoverProvider": true,
+		"completionProvider": {"triggerCharacters": ["."]},
+		"codeActionProvider": {"codeActionKinds": ["quickfix"]},
+		"documentFormattingProvider": false,
+		"semanticTokensProvider": {"legend": {"tokenTypes": [], "tokenModifiers": []}, "full": {"delta": false}}
+	}`
+	if err := json.Unmarshal([]byte(raw), &caps); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{MethodHover, true},
+		{MethodCompletion, true},
+		{MethodSignatureHelp, false},
+		{MethodCodeAction, true},
+		{MethodFormatting, false},
+		{MethodRangeFormatting, false},
+		{MethodSemanticTokensFull, true},
+		{MethodExecuteCommand, false},
+		{MethodDidChangeWorkspaceFolders, false},
+		{MethodDidChangeWatchedFiles, true},
+		{"textDocument/unknown", false},
+	}
+	for _, tt := range tests {
+		if got := caps.Supports(tt.method); got != tt.want {
+			t.Errorf("Supports(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+
+	// 零值不支持任何依赖服务端声明的功能
+	if (ServerCapabilities{}).Supports(MethodHover) {
+		t.Error("zero capabilities should not support hover")
+	}
+}
+
+func TestHoverText(t *testing.T) {
+	tests := []struct {
+		contents string
+		want     string
+	}{
+		{`{"kind":"markdown","value":"func Println()"}`, "func Println()"},
+		{`"plain"`, "plain"},
+		{`{"language":"go","value":"var x int"}`, "var x int"},
+		{`["a", {"language":"go","value":"b"}]`, "a\nb"},
+		{`null`, ""},
+		{`42`, ""},
+	}
+	for _, tt := range tests {
+		if got := (Hover{Contents: json.RawMessage(tt.contents)}).HoverText(); got != tt.want {
+			t.Errorf("HoverText(%s) = %q, want %q", tt.contents, got, tt.want)
+		}
+	}
+}
+
+func TestUnsupportedError_Is(t *testing.T) {
+	err := fmt.Errorf("hover: %w", &UnsupportedError{Method: MethodHover})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Error("wrapped UnsupportedError should match ErrUnsupported")
+	}
+	var ue *UnsupportedError
+	if !errors.As(err, &ue) || ue.Method != MethodHover {
+		t.Errorf("errors.As = %v", ue)
+	}
+	if errors.Is(errors.New("other"), ErrUnsupported) {
+		t.Error("unrelated error should not match ErrUnsupported")
+	}
+}
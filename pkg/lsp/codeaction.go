@@ -0,0 +1,324 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	MethodCodeAction         = "textDocument/codeAction"
+	MethodCodeActionResolve  = "codeAction/resolve"
+	MethodExecuteCommand     = "workspace/executeCommand"
+	MethodApplyEdit          = "workspace/applyEdit"
+	MethodPublishDiagnostics = "textDocument/publishDiagnostics"
+)
+
+// 常用的代码操作类型
+const (
+	CodeActionQuickFix              = "quickfix"
+	CodeActionRefactor              = "refactor"
+	CodeActionSource                = "source"
+	CodeActionSourceOrganizeImports = "source.organizeImports"
+	CodeActionSourceFixAll          = "source.fixAll"
+)
+
+type Diagnostic struct {
+	Range    Range           `json:"range"`
+	Severity int             `json:"severity,omitempty"`
+	Code     interface{}     `json:"code,omitempty"`
+	Source   string          `json:"source,omitempty"`
+	Message  string          `json:"message"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+type CodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind,omitempty"`
+	Diagnostics []Diagnostic    `json:"diagnostics,omitempty"`
+	IsPreferred bool            `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit  `json:"edit,omitempty"`
+	Command     *Command        `json:"command,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+type CodeActionOptions struct {
+	CodeActionKinds []string `json:"codeActionKinds,omitempty"`
+	ResolveProvider bool     `json:"resolveProvider,omitempty"`
+}
+
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+// diagnosticsEntry 记录某个文档最近一次发布的诊断
+type diagnosticsEntry struct {
+	version     *int
+	diagnostics []Diagnostic
+}
+
+// ApplyEditHandler 处理服务端发起的 workspace/applyEdit 请求
+type ApplyEditHandler func(edit WorkspaceEdit) error
+
+// CodeActions 获取指定范围内可用的代码操作，only 用于限定操作类型，如 CodeActionQuickFix
+func (c *LSPClient) CodeActions(ctx context.Context, uri string, rng Range, diagnostics []Diagnostic, only ...string) ([]CodeAction, error) {
+	if err := c.require(MethodCodeAction); err != nil {
+		return nil, err
+	}
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	actionContext := map[string]interface{}{
+		"diagnostics": diagnostics,
+	}
+	if len(only) > 0 {
+		actionContext["only"] = only
+	}
+	params := map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+		"range":        rng,
+		"context":      actionContext,
+	}
+	result, err := c.sendRequest(ctx, MethodCodeAction, params)
+	if err != nil {
+		return nil, err
+	}
+	if isNullResult(result) {
+		return nil, nil
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(result, &raws); err != nil {
+		return nil, fmt.Errorf("解析codeAction结果失败: %w", err)
+	}
+	actions := make([]CodeAction, 0, len(raws))
+	for _, raw := range raws {
+		// 结果可能是 Command 或 CodeAction，Command 的 command 字段为字符串
+		var probe struct {
+			Command json.RawMessage `json:"command"`
+		}
+		_ = json.Unmarshal(raw, &probe)
+		if len(probe.Command) > 0 && probe.Command[0] == '"' {
+			var cmd Command
+			if err := json.Unmarshal(raw, &cmd); err != nil {
+				return nil, fmt.Errorf("解析codeAction命令失败: %w", err)
+			}
+			actions = append(actions, CodeAction{Title: cmd.Title, Command: &cmd})
+			continue
+		}
+		var action CodeAction
+		if err := json.Unmarshal(raw, &action); err != nil {
+			return nil, fmt.Errorf("解析codeAction失败: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// ResolveCodeAction 在服务端支持时补全代码操作的 edit 字段，不支持或无需补全时原样返回
+func (c *LSPClient) ResolveCodeAction(ctx context.Context, action CodeAction) (CodeAction, error) {
+	if action.Edit != nil || len(action.Data) == 0 {
+		return action, nil
+	}
+	caps := c.Capabilities()
+	var options CodeActionOptions
+	if err := json.Unmarshal(caps.CodeActionProvider, &options); err != nil || !options.ResolveProvider {
+		return action, nil
+	}
+	result, err := c.sendRequest(ctx, MethodCodeActionResolve, action)
+	if err != nil {
+		return action, err
+	}
+	var resolved CodeAction
+	if err := json.Unmarshal(result, &resolved); err != nil {
+		return action, fmt.Errorf("解析codeAction/resolve结果失败: %w", err)
+	}
+	return resolved, nil
+}
+
+// ExecuteCommand 执行服务端命令，命令产生的修改会通过 workspace/applyEdit 回传
+func (c *LSPClient) ExecuteCommand(ctx context.Context, cmd Command) (json.RawMessage, error) {
+	if err := c.require(MethodExecuteCommand); err != nil {
+		return nil, err
+	}
+	arguments := cmd.Arguments
+	if arguments == nil {
+		arguments = []interface{}{}
+	}
+	params := map[string]interface{}{
+		"command":   cmd.Command,
+		"arguments": arguments,
+	}
+	return c.sendRequest(ctx, MethodExecuteCommand, params)
+}
+
+// Diagnostics 返回服务端最近一次为 uri 发布的诊断
+func (c *LSPClient) Diagnostics(uri string) []Diagnostic {
+	c.diagnosticsMutex.RLock()
+	defer c.diagnosticsMutex.RUnlock()
+	entry, ok := c.diagnostics[uri]
+	if !ok {
+		return nil
+	}
+	diagnostics := make([]Diagnostic, len(entry.diagnostics))
+	copy(diagnostics, entry.diagnostics)
+	return diagnostics
+}
+
+// WaitForDiagnostics 等待服务端发布不早于文档当前版本的诊断，超时返回 ctx 的错误
+func (c *LSPClient) WaitForDiagnostics(ctx context.Context, uri string) ([]Diagnostic, error) {
+	version := 0
+	c.documentsMutex.RLock()
+	if doc, ok := c.documents[uri]; ok {
+		version = doc.version
+	}
+	c.documentsMutex.RUnlock()
+
+	for {
+		c.diagnosticsMutex.RLock()
+		entry, ok := c.diagnostics[uri]
+		signal := c.diagnosticsSignal
+		c.diagnosticsMutex.RUnlock()
+		// 未携带版本号的诊断无法判断新旧，视为最新
+		if ok && (entry.version == nil || *entry.version >= version) {
+			return c.Diagnostics(uri), nil
+		}
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			return c.Diagnostics(uri), ctx.Err()
+		}
+	}
+}
+
+func (c *LSPClient) handlePublishDiagnostics(params interface{}) {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		logger.Errorf("Failed to marshal publishDiagnostics params: %v", err)
+		return
+	}
+	var p struct {
+		URI         string       `json:"uri"`
+		Version     *int         `json:"version,omitempty"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(paramsBytes, &p); err != nil {
+		logger.Errorf("Failed to unmarshal publishDiagnostics params: %v", err)
+		return
+	}
+	c.diagnosticsMutex.Lock()
+	c.diagnostics[p.URI] = diagnosticsEntry{version: p.Version, diagnostics: p.Diagnostics}
+	// 关闭旧信号以唤醒所有等待者
+	close(c.diagnosticsSignal)
+	c.diagnosticsSignal = make(chan struct{})
+	c.diagnosticsMutex.Unlock()
+}
+
+// ExecuteCommandForDocument 执行命令，执行期间服务端发起的涉及 uri 的 workspace/applyEdit 交给 handler 处理，
+// 不影响其它文档以及 SetApplyEditHandler 设置的处理方式。同一文档同时只能有一个命令在执行
+func (c *LSPClient) ExecuteCommandForDocument(ctx context.Context, uri string, cmd Command, handler ApplyEditHandler) (json.RawMessage, error) {
+	c.applyEditMutex.Lock()
+	if _, busy := c.documentEditHandlers[uri]; busy {
+		c.applyEditMutex.Unlock()
+		return nil, fmt.Errorf("文档 %s 正在执行其它命令", uri)
+	}
+	if c.documentEditHandlers == nil {
+		c.documentEditHandlers = make(map[string]ApplyEditHandler)
+	}
+	c.documentEditHandlers[uri] = handler
+	c.applyEditMutex.Unlock()
+
+	defer func() {
+		c.applyEditMutex.Lock()
+		delete(c.documentEditHandlers, uri)
+		c.applyEditMutex.Unlock()
+	}()
+	return c.ExecuteCommand(ctx, cmd)
+}
+
+// SetApplyEditHandler 自定义 workspace/applyEdit 的处理方式，传入 nil 恢复为 ApplyWorkspaceEdit
+func (c *LSPClient) SetApplyEditHandler(h ApplyEditHandler) {
+	c.applyEditMutex.Lock()
+	c.applyEditHandler = h
+	c.applyEditMutex.Unlock()
+}
+
+func (c *LSPClient) handleApplyEdit(params interface{}) (interface{}, error) {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var p struct {
+		Label string        `json:"label,omitempty"`
+		Edit  WorkspaceEdit `json:"edit"`
+	}
+	if err := json.Unmarshal(paramsBytes, &p); err != nil {
+		return nil, err
+	}
+	c.applyEditMutex.RLock()
+	handler := c.applyEditHandler
+	// 优先交给正在执行命令的文档处理
+	for uri := range p.Edit.TextEdits() {
+		if h, ok := c.documentEditHandlers[uri]; ok {
+			handler = h
+			break
+		}
+	}
+	c.applyEditMutex.RUnlock()
+	if handler == nil {
+		handler = func(edit WorkspaceEdit) error {
+			return c.ApplyWorkspaceEdit(context.Background(), edit)
+		}
+	}
+	result := map[string]interface{}{"applied": true}
+	if err := handler(p.Edit); err != nil {
+		result["applied"] = false
+		result["failureReason"] = err.Error()
+	}
+	return result, nil
+}
+
+// ApplyWorkspaceEdit 应用工作区修改：已打开的文档同步给服务端，其余文件直接改写磁盘
+func (c *LSPClient) ApplyWorkspaceEdit(ctx context.Context, edit WorkspaceEdit) error {
+	for uri, edits := range edit.TextEdits() {
+		path, err := URIToPath(uri)
+		if err != nil {
+			return err
+		}
+		if text, ok := c.DocumentText(uri); ok {
+			newText, err := ApplyTextEdits(text, edits)
+			if err != nil {
+				return err
+			}
+			if err := c.ChangeDocument(ctx, path, newText); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		newText, err := ApplyTextEdits(string(content), edits)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(newText), info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := c.NotifyFileChanged(ctx, FileChanged, path); err != nil {
+			logger.Warnf("通知文件变更失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package lsp
+
+import "testing"
+
+// Test: workspace/applyEdit 涉及正在执行命令的文档时交给该文档的处理方法
+func TestHandleApplyEdit_DocumentHandler(t *testing.T) {
+	var global, scoped int
+	c := &LSPClient{}
+	c.SetApplyEditHandler(func(edit WorkspaceEdit) error {
+		global++
+		return nil
+	})
+	c.documentEditHandlers = map[string]ApplyEditHandler{
+		"file:///a.go": func(edit WorkspaceEdit) error {
+			scoped++
+			return nil
+		},
+	}
+	params := func(uri string) map[string]interface{} {
+		return map[string]interface{}{
+			"edit": map[string]interface{}{
+				"changes": map[string]interface{}{
+					uri: []TextEdit{{Range: Range{Start: pos(0, 0), End: pos(0, 0)}, NewText: "x"}},
+				},
+			},
+		}
+	}
+	if _, err := c.handleApplyEdit(params("file:///a.go")); err != nil {
+		t.Fatalf("handleApplyEdit error: %v", err)
+	}
+	if _, err := c.handleApplyEdit(params("file:///b.go")); err != nil {
+		t.Fatalf("handleApplyEdit error: %v", err)
+	}
+	if scoped != 1 || global != 1 {
+		t.Errorf("scoped = %d, global = %d, want 1 and 1", scoped, global)
+	}
+}
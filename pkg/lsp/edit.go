@@ -0,0 +1,184 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEdit 工作区修改，仅处理文本修改，创建、重命名、删除文件的操作会被忽略
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage     `json:"documentChanges,omitempty"`
+}
+
+// TextEdits 按文档 URI 汇总 Changes 与 DocumentChanges 中的文本修改
+func (e WorkspaceEdit) TextEdits() map[string][]TextEdit {
+	edits := make(map[string][]TextEdit)
+	for uri, changes := range e.Changes {
+		edits[uri] = append(edits[uri], changes...)
+	}
+	for _, raw := range e.DocumentChanges {
+		var change TextDocumentEdit
+		if err := json.Unmarshal(raw, &change); err != nil || change.TextDocument.URI == "" {
+			// CreateFile / RenameFile / DeleteFile 等资源操作
+			continue
+		}
+		edits[change.TextDocument.URI] = append(edits[change.TextDocument.URI], change.Edits...)
+	}
+	return edits
+}
+
+// ComparePosition 比较两个位置，a 在 b 之前返回负数，相同返回 0
+func ComparePosition(a, b Position) int {
+	if a.Line != b.Line {
+		return a.Line - b.Line
+	}
+	return a.Character - b.Character
+}
+
+// PositionOffset 将位置转换为 text 中的字节偏移，Character 以 UTF-16 编码单元计
+func PositionOffset(text string, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		idx := strings.IndexByte(text[offset:], '\n')
+		if idx < 0 {
+			return 0, fmt.Errorf("位置超出文本范围: %d:%d", pos.Line, pos.Character)
+		}
+		offset += idx + 1
+	}
+	lineEnd := strings.IndexByte(text[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text) - offset
+	}
+	units := 0
+	for i, r := range text[offset : offset+lineEnd] {
+		if units >= pos.Character {
+			return offset + i, nil
+		}
+		units += utf16.RuneLen(r)
+	}
+	return offset + lineEnd, nil
+}
+
+// ApplyTextEdits 将一组互不重叠的文本修改应用到 text 上
+func ApplyTextEdits(text string, edits []TextEdit) (string, error) {
+	type offsetEdit struct {
+		start, end int
+		newText    string
+	}
+	resolved := make([]offsetEdit, 0, len(edits))
+	for _, edit := range edits {
+		start, err := PositionOffset(text, edit.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := PositionOffset(text, edit.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("无效的修改范围: %v", edit.Range)
+		}
+		resolved = append(resolved, offsetEdit{start: start, end: end, newText: edit.NewText})
+	}
+	// 从后往前应用，避免前面的修改影响后面的偏移
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return resolved[i].start > resolved[j].start
+	})
+	for i := 1; i < len(resolved); i++ {
+		if resolved[i].end > resolved[i-1].start {
+			return "", fmt.Errorf("文本修改范围存在重叠")
+		}
+	}
+	for _, edit := range resolved {
+		text = text[:edit.start] + edit.newText + text[edit.end:]
+	}
+	return text, nil
+}
+
+// TransformPosition 计算应用 edits 之后 pos 所在的新位置。
+// 位于某个修改范围内部的位置会被移动到该修改新文本的末尾，恰好在 pos 处的插入会使 pos 后移。
+func TransformPosition(pos Position, edits []TextEdit) Position {
+	return transformPosition(pos, edits, false)
+}
+
+// TransformRange 计算应用 edits 之后 r 的新范围，紧贴范围两端的插入会被包含在范围内
+func TransformRange(r Range, edits []TextEdit) Range {
+	return Range{
+		Start: transformPosition(r.Start, edits, true),
+		End:   transformPosition(r.End, edits, false),
+	}
+}
+
+// transformPosition stickLeft 为 true 时，从 pos 处开始的修改不移动 pos
+func transformPosition(pos Position, edits []TextEdit, stickLeft bool) Position {
+	sorted := make([]TextEdit, len(edits))
+	copy(sorted, edits)
+	// 从后往前处理，保证每个修改的范围仍以原始坐标表示
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ComparePosition(sorted[i].Range.Start, sorted[j].Range.Start) > 0
+	})
+	for _, edit := range sorted {
+		start, end := edit.Range.Start, edit.Range.End
+		if ComparePosition(start, pos) > 0 {
+			continue
+		}
+		if stickLeft && ComparePosition(start, pos) == 0 {
+			continue
+		}
+		if ComparePosition(end, pos) > 0 {
+			// pos 位于修改范围内部
+			pos = end
+		}
+		lines := strings.Split(edit.NewText, "\n")
+		lastLineUnits := len(utf16.Encode([]rune(lines[len(lines)-1])))
+		if pos.Line == end.Line {
+			if len(lines) == 1 {
+				pos.Character = start.Character + lastLineUnits + (pos.Character - end.Character)
+			} else {
+				pos.Character = lastLineUnits + (pos.Character - end.Character)
+			}
+		}
+		pos.Line += (len(lines) - 1) - (end.Line - start.Line)
+	}
+	return pos
+}
+
+// TextInRange 返回 text 中 r 范围内的内容
+func TextInRange(text string, r Range) (string, error) {
+	start, err := PositionOffset(text, r.Start)
+	if err != nil {
+		return "", err
+	}
+	end, err := PositionOffset(text, r.End)
+	if err != nil {
+		return "", err
+	}
+	if end < start {
+		return "", fmt.Errorf("无效的范围: %v", r)
+	}
+	return text[start:end], nil
+}
@@ -0,0 +1,75 @@
+package lsp
+
+import "testing"
+
+func pos(line, character int) Position {
+	return Position{Line: line, Character: character}
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	text := "package main\n\nfunc main() {\n\tx := 1\n}\n"
+	edits := []TextEdit{
+		{Range: Range{Start: pos(1, 0), End: pos(1, 0)}, NewText: "import \"fmt\"\n"},
+		{Range: Range{Start: pos(3, 1), End: pos(3, 2)}, NewText: "y"},
+	}
+	got, err := ApplyTextEdits(text, edits)
+	if err != nil {
+		t.Fatalf("ApplyTextEdits error: %v", err)
+	}
+	want := "package main\nimport \"fmt\"\n\nfunc main() {\n\ty := 1\n}\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestApplyTextEdits_Overlap(t *testing.T) {
+	edits := []TextEdit{
+		{Range: Range{Start: pos(0, 0), End: pos(0, 3)}, NewText: "a"},
+		{Range: Range{Start: pos(0, 2), End: pos(0, 4)}, NewText: "b"},
+	}
+	if _, err := ApplyTextEdits("abcdef", edits); err == nil {
+		t.Fatalf("expected overlap error")
+	}
+}
+
+// Test: 中文字符按 UTF-16 计算列
+func TestPositionOffset_UTF16(t *testing.T) {
+	text := "s := \"中文\" + x"
+	offset, err := PositionOffset(text, pos(0, 9))
+	if err != nil {
+		t.Fatalf("PositionOffset error: %v", err)
+	}
+	if got := text[offset:]; got != " + x" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestTransformRange(t *testing.T) {
+	// 输入位于第 3 行第 1 列：\tx := 1
+	r := Range{Start: pos(3, 1), End: pos(3, 7)}
+	edits := []TextEdit{
+		// 在上方插入两行 import
+		{Range: Range{Start: pos(1, 0), End: pos(1, 0)}, NewText: "import (\n\t\"fmt\"\n)\n"},
+		// 在输入开头插入
+		{Range: Range{Start: pos(3, 1), End: pos(3, 1)}, NewText: "var _ = 0; "},
+		// 在输入末尾追加
+		{Range: Range{Start: pos(3, 7), End: pos(3, 7)}, NewText: "; _ = x"},
+	}
+	got := TransformRange(r, edits)
+	want := Range{Start: pos(6, 1), End: pos(6, 25)}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	text := "package main\n\nfunc main() {\n\tx := 1\n}\n"
+	newText, err := ApplyTextEdits(text, edits)
+	if err != nil {
+		t.Fatalf("ApplyTextEdits error: %v", err)
+	}
+	input, err := TextInRange(newText, got)
+	if err != nil {
+		t.Fatalf("TextInRange error: %v", err)
+	}
+	if input != "var _ = 0; x := 1; _ = x" {
+		t.Fatalf("input %q", input)
+	}
+}
@@ -0,0 +1,62 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	MethodFormatting      = "textDocument/formatting"
+	MethodRangeFormatting = "textDocument/rangeFormatting"
+)
+
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+// DefaultFormattingOptions Go 代码使用 tab 缩进
+func DefaultFormattingOptions() FormattingOptions {
+	return FormattingOptions{TabSize: 4, InsertSpaces: false}
+}
+
+// Formatting 格式化整个文档，返回需要应用的修改
+func (c *LSPClient) Formatting(ctx context.Context, uri string, options FormattingOptions) ([]TextEdit, error) {
+	if err := c.require(MethodFormatting); err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+		"options":      options,
+	}
+	return c.formattingRequest(ctx, MethodFormatting, params)
+}
+
+// RangeFormatting 格式化文档中的指定范围，返回需要应用的修改
+func (c *LSPClient) RangeFormatting(ctx context.Context, uri string, rng Range, options FormattingOptions) ([]TextEdit, error) {
+	if err := c.require(MethodRangeFormatting); err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+		"range":        rng,
+		"options":      options,
+	}
+	return c.formattingRequest(ctx, MethodRangeFormatting, params)
+}
+
+func (c *LSPClient) formattingRequest(ctx context.Context, method string, params interface{}) ([]TextEdit, error) {
+	result, err := c.sendRequest(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if isNullResult(result) {
+		return nil, nil
+	}
+	var edits []TextEdit
+	if err := json.Unmarshal(result, &edits); err != nil {
+		return nil, fmt.Errorf("解析%s结果失败: %w", method, err)
+	}
+	return edits, nil
+}
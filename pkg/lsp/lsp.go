@@ -50,6 +50,55 @@ type CompletionList struct {
 	Items        []CompletionItem `json:"items"`
 }
 
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	// Contents 可能是 MarkedString、MarkedString[] 或 MarkupContent，使用 HoverText 获取文本
+	Contents json.RawMessage `json:"contents"`
+}
+
+// HoverText 将不同形式的 hover 内容统一转换为纯文本
+func (h Hover) HoverText() string {
+	var markup MarkupContent
+	if err := json.Unmarshal(h.Contents, &markup); err == nil && markup.Value != "" {
+		return markup.Value
+	}
+	var text string
+	if err := json.Unmarshal(h.Contents, &text); err == nil {
+		return text
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(h.Contents, &list); err == nil {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			parts = append(parts, Hover{Contents: item}.HoverText())
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+type ParameterInformation struct {
+	// Label 可能是字符串或 [start, end] 偏移量
+	Label         json.RawMessage `json:"label"`
+	Documentation interface{}     `json:"documentation,omitempty"`
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation interface{}            `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters,omitempty"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature,omitempty"`
+	ActiveParameter int                    `json:"activeParameter,omitempty"`
+}
+
 type JSONRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
 	ID      int         `json:"id"`
@@ -95,6 +144,23 @@ type LSPClient struct {
 	isReady         bool
 	readyChan       chan struct{}
 	readyMutex      sync.RWMutex
+
+	capabilities      ServerCapabilities
+	serverInfo        *ServerInfo
+	capabilitiesMutex sync.RWMutex
+
+	documents        map[string]*document
+	documentsMutex   sync.RWMutex
+	workspaceFolders []WorkspaceFolder
+	workspaceMutex   sync.RWMutex
+
+	diagnostics       map[string]diagnosticsEntry
+	diagnosticsSignal chan struct{}
+	diagnosticsMutex  sync.RWMutex
+	applyEditHandler  ApplyEditHandler
+	// documentEditHandlers 按文档 URI 记录 ExecuteCommandForDocument 期间的处理方式
+	documentEditHandlers map[string]ApplyEditHandler
+	applyEditMutex       sync.RWMutex
 }
 
 // NewLSPClient creates a new LSP client
@@ -128,13 +194,19 @@ func NewLSPClient(ctx context.Context, workspace, filePath string) (*LSPClient,
 	logger.Debugf("gopls进程已启动，PID: %d", cmd.Process.Pid)
 
 	client := &LSPClient{
-		stdin:           stdin,
-		stdout:          stdout,
-		cmd:             cmd,
-		workspacePath:   "file://" + workspace,
-		fileURI:         "file://" + filePath,
-		pendingRequests: make(map[int]chan *JSONRPCResponse),
-		readyChan:       make(chan struct{}),
+		stdin:             stdin,
+		stdout:            stdout,
+		cmd:               cmd,
+		workspacePath:     PathToURI(workspace),
+		fileURI:           PathToURI(filePath),
+		pendingRequests:   make(map[int]chan *JSONRPCResponse),
+		readyChan:         make(chan struct{}),
+		documents:         make(map[string]*document),
+		diagnostics:       make(map[string]diagnosticsEntry),
+		diagnosticsSignal: make(chan struct{}),
+		workspaceFolders: []WorkspaceFolder{
+			NewWorkspaceFolder(workspace),
+		},
 	}
 
 	go client.reader()
@@ -193,10 +265,67 @@ func (c *LSPClient) reader() {
 				continue
 			}
 			c.handleNotification(&notif)
+		} else if baseMessage.ID != nil && baseMessage.Method != nil { // It's a request from server
+			var req JSONRPCRequest
+			if err := json.Unmarshal(b, &req); err != nil {
+				logger.Errorf("Failed to unmarshal LSP server request: %v", err)
+				continue
+			}
+			go c.handleServerRequest(&req)
 		}
 	}
 }
 
+// handleServerRequest 响应服务端发起的请求，未实现的方法返回 MethodNotFound
+func (c *LSPClient) handleServerRequest(req *JSONRPCRequest) {
+	var (
+		result interface{}
+		rpcErr *JSONRPCError
+	)
+	switch req.Method {
+	case "client/registerCapability", "client/unregisterCapability", "window/workDoneProgress/create":
+		result = nil
+	case "workspace/workspaceFolders":
+		result = c.WorkspaceFolders()
+	case MethodApplyEdit:
+		var err error
+		result, err = c.handleApplyEdit(req.Params)
+		if err != nil {
+			rpcErr = &JSONRPCError{Code: -32602, Message: err.Error()}
+		}
+	case "workspace/configuration":
+		// 不提供任何自定义配置，按请求项数量返回 null
+		var params struct {
+			Items []interface{} `json:"items"`
+		}
+		paramsBytes, _ := json.Marshal(req.Params)
+		_ = json.Unmarshal(paramsBytes, &params)
+		result = make([]interface{}, len(params.Items))
+	default:
+		rpcErr = &JSONRPCError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+	if err := c.sendResponse(req.ID, result, rpcErr); err != nil {
+		logger.Warnf("回复服务端请求 %s 失败: %v", req.Method, err)
+	}
+}
+
+func (c *LSPClient) sendResponse(id int, result interface{}, rpcErr *JSONRPCError) error {
+	resp := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	respData, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("序列化响应失败: %w", err)
+	}
+	return c.sendMessage(respData)
+}
+
 func (c *LSPClient) handleNotification(n *JSONRPCNotification) {
 	switch n.Method {
 	case "window/showMessage":
@@ -218,11 +347,13 @@ func (c *LSPClient) handleNotification(n *JSONRPCNotification) {
 					c.isReady = true
 					close(c.readyChan)
 				}
-			c.readyMutex.Unlock()
+				c.readyMutex.Unlock()
 			}
 		}
 	case "window/logMessage":
 		logger.Infof("[gopls log]: %s", n.Params)
+	case MethodPublishDiagnostics:
+		c.handlePublishDiagnostics(n.Params)
 	default:
 		// unhandled
 	}
@@ -305,17 +436,31 @@ func (c *LSPClient) sendNotification(method string, params interface{}) error {
 func (c *LSPClient) initialize(ctx context.Context) error {
 	logger.Debugf("开始初始化LSP连接...")
 	params := map[string]interface{}{
-		"processId": os.Getpid(),
-		"rootUri":   c.workspacePath,
-		"capabilities":  map[string]interface{}{},
+		"processId":        os.Getpid(),
+		"rootUri":          c.workspacePath,
+		"workspaceFolders": c.WorkspaceFolders(),
+		"capabilities":     clientCapabilities(),
+		// gopls 默认关闭语义标记，需要通过初始化选项开启
+		"initializationOptions": map[string]interface{}{
+			"semanticTokens": true,
+		},
 	}
 
-	_, err := c.sendRequest(ctx, "initialize", params)
+	result, err := c.sendRequest(ctx, "initialize", params)
 	if err != nil {
 		return fmt.Errorf("发送initialize请求失败: %w", err)
 	}
 	logger.Debugf("收到initialize响应")
 
+	var initResult InitializeResult
+	if err := json.Unmarshal(result, &initResult); err != nil {
+		return fmt.Errorf("解析initialize响应失败: %w", err)
+	}
+	c.capabilitiesMutex.Lock()
+	c.capabilities = initResult.Capabilities
+	c.serverInfo = initResult.ServerInfo
+	c.capabilitiesMutex.Unlock()
+
 	return c.sendNotification("initialized", map[string]interface{}{})
 }
 
@@ -323,6 +468,45 @@ func (c *LSPClient) GetFileURI() string {
 	return c.fileURI
 }
 
+// Capabilities 返回服务端在 initialize 时声明的能力
+func (c *LSPClient) Capabilities() ServerCapabilities {
+	c.capabilitiesMutex.RLock()
+	defer c.capabilitiesMutex.RUnlock()
+	return c.capabilities
+}
+
+// ServerInfo 返回服务端名称与版本，服务端未提供时为 nil
+func (c *LSPClient) ServerInfo() *ServerInfo {
+	c.capabilitiesMutex.RLock()
+	defer c.capabilitiesMutex.RUnlock()
+	return c.serverInfo
+}
+
+// Supports 判断服务端是否支持指定的 LSP 方法，如 MethodHover
+func (c *LSPClient) Supports(method string) bool {
+	return c.Capabilities().Supports(method)
+}
+
+// require 在服务端不支持 method 时返回 *UnsupportedError
+func (c *LSPClient) require(method string) error {
+	if !c.Supports(method) {
+		return &UnsupportedError{Method: method}
+	}
+	return nil
+}
+
+func positionParams(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: uri,
+		},
+		Position: Position{
+			Line:      line,
+			Character: character,
+		},
+	}
+}
+
 func (c *LSPClient) sendMessage(message []byte) error {
 	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(message))
 	logger.Debugf("发送消息: %s%s", header, string(message))
@@ -378,21 +562,22 @@ func (c *LSPClient) DidOpen(ctx context.Context, filename, languageID string, ve
 	return c.sendNotification("textDocument/didOpen", params)
 }
 
+// GetCompletions 获取默认文档（创建客户端时指定的文件）中光标位置的补全
 func (c *LSPClient) GetCompletions(ctx context.Context, line, character int) (*CompletionList, error) {
-	logger.Debugf("===== 光标位置 行: %d 列: %d", line, character)
+	return c.GetCompletionsAt(ctx, c.fileURI, line, character)
+}
+
+// GetCompletionsAt 获取指定文档中光标位置的补全
+func (c *LSPClient) GetCompletionsAt(ctx context.Context, uri string, line, character int) (*CompletionList, error) {
+	logger.Debugf("===== 光标位置 %s 行: %d 列: %d", uri, line, character)
+	if err := c.require(MethodCompletion); err != nil {
+		return nil, err
+	}
 	params := CompletionParams{
-		TextDocumentPositionParams: TextDocumentPositionParams{
-			TextDocument: TextDocumentIdentifier{
-				URI: c.fileURI,
-			},
-			Position: Position{
-				Line:      line,
-				Character: character,
-			},
-		},
+		TextDocumentPositionParams: positionParams(uri, line, character),
 	}
 
-	result, err := c.sendRequest(ctx, "textDocument/completion", params)
+	result, err := c.sendRequest(ctx, MethodCompletion, params)
 	if err != nil {
 		return nil, err
 	}
@@ -406,6 +591,59 @@ func (c *LSPClient) GetCompletions(ctx context.Context, line, character int) (*C
 	return &completionList, nil
 }
 
+// Hover 获取默认文档中光标位置的悬浮提示，服务端无结果时返回 nil
+func (c *LSPClient) Hover(ctx context.Context, line, character int) (*Hover, error) {
+	return c.HoverAt(ctx, c.fileURI, line, character)
+}
+
+// HoverAt 获取指定文档中光标位置的悬浮提示
+func (c *LSPClient) HoverAt(ctx context.Context, uri string, line, character int) (*Hover, error) {
+	if err := c.require(MethodHover); err != nil {
+		return nil, err
+	}
+	result, err := c.sendRequest(ctx, MethodHover, positionParams(uri, line, character))
+	if err != nil {
+		return nil, err
+	}
+	if isNullResult(result) {
+		return nil, nil
+	}
+	var hover Hover
+	if err := json.Unmarshal(result, &hover); err != nil {
+		return nil, fmt.Errorf("解析hover结果失败: %w", err)
+	}
+	return &hover, nil
+}
+
+// SignatureHelp 获取默认文档中光标位置的函数签名提示，服务端无结果时返回 nil
+func (c *LSPClient) SignatureHelp(ctx context.Context, line, character int) (*SignatureHelp, error) {
+	return c.SignatureHelpAt(ctx, c.fileURI, line, character)
+}
+
+// SignatureHelpAt 获取指定文档中光标位置的函数签名提示
+func (c *LSPClient) SignatureHelpAt(ctx context.Context, uri string, line, character int) (*SignatureHelp, error) {
+	if err := c.require(MethodSignatureHelp); err != nil {
+		return nil, err
+	}
+	result, err := c.sendRequest(ctx, MethodSignatureHelp, positionParams(uri, line, character))
+	if err != nil {
+		return nil, err
+	}
+	if isNullResult(result) {
+		return nil, nil
+	}
+	var help SignatureHelp
+	if err := json.Unmarshal(result, &help); err != nil {
+		return nil, fmt.Errorf("解析signatureHelp结果失败: %w", err)
+	}
+	return &help, nil
+}
+
+func isNullResult(result json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(result))
+	return trimmed == "" || trimmed == "null"
+}
+
 func (c *LSPClient) Close() error {
 	if err := c.sendNotification("exit", nil); err != nil {
 		logger.Warnf("Failed to send exit notification: %v", err)
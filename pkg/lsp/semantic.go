@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const MethodSemanticTokensFull = "textDocument/semanticTokens/full"
+
+// 客户端能够识别的语义标记类型与修饰符
+var (
+	semanticTokenTypes = []string{
+		"namespace", "type", "class", "enum", "interface", "struct", "typeParameter",
+		"parameter", "variable", "property", "enumMember", "event", "function",
+		"method", "macro", "keyword", "modifier", "comment", "string", "number",
+		"regexp", "operator", "decorator", "label",
+	}
+	semanticTokenModifiers = []string{
+		"declaration", "definition", "readonly", "static", "deprecated", "abstract",
+		"async", "modification", "documentation", "defaultLibrary",
+	}
+)
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	// Range 与 Full 可能是 bool 或对象
+	Range json.RawMessage `json:"range,omitempty"`
+	Full  json.RawMessage `json:"full,omitempty"`
+}
+
+type SemanticTokens struct {
+	ResultID string `json:"resultId,omitempty"`
+	Data     []int  `json:"data"`
+}
+
+// SemanticToken 是解码后的单个语义标记，Character 与 Length 以 UTF-16 编码单元计
+type SemanticToken struct {
+	Line      int
+	Character int
+	Length    int
+	Type      string
+	Modifiers []string
+}
+
+// HasModifier 判断标记是否带有指定修饰符
+func (t SemanticToken) HasModifier(modifier string) bool {
+	for _, m := range t.Modifiers {
+		if m == modifier {
+			return true
+		}
+	}
+	return false
+}
+
+// SemanticTokensFull 获取整个文档的语义标记
+func (c *LSPClient) SemanticTokensFull(ctx context.Context, uri string) (*SemanticTokens, error) {
+	if err := c.require(MethodSemanticTokensFull); err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+	}
+	result, err := c.sendRequest(ctx, MethodSemanticTokensFull, params)
+	if err != nil {
+		return nil, err
+	}
+	if isNullResult(result) {
+		return &SemanticTokens{}, nil
+	}
+	var tokens SemanticTokens
+	if err := json.Unmarshal(result, &tokens); err != nil {
+		return nil, fmt.Errorf("解析semanticTokens结果失败: %w", err)
+	}
+	return &tokens, nil
+}
+
+// SemanticTokenLegend 返回服务端声明的语义标记图例
+func (c *LSPClient) SemanticTokenLegend() SemanticTokensLegend {
+	caps := c.Capabilities()
+	if caps.SemanticTokensProvider == nil {
+		return SemanticTokensLegend{}
+	}
+	return caps.SemanticTokensProvider.Legend
+}
+
+// DecodeSemanticTokens 将按 5 个整数一组、相对编码的 data 解码为绝对位置的标记
+func DecodeSemanticTokens(data []int, legend SemanticTokensLegend) []SemanticToken {
+	tokens := make([]SemanticToken, 0, len(data)/5)
+	line, char := 0, 0
+	for i := 0; i+4 < len(data); i += 5 {
+		deltaLine, deltaStart, length, typeIndex, modifierBits := data[i], data[i+1], data[i+2], data[i+3], data[i+4]
+		if deltaLine > 0 {
+			line += deltaLine
+			char = deltaStart
+		} else {
+			char += deltaStart
+		}
+		token := SemanticToken{
+			Line:      line,
+			Character: char,
+			Length:    length,
+		}
+		if typeIndex >= 0 && typeIndex < len(legend.TokenTypes) {
+			token.Type = legend.TokenTypes[typeIndex]
+		}
+		for bit := 0; bit < len(legend.TokenModifiers); bit++ {
+			if modifierBits&(1<<bit) != 0 {
+				token.Modifiers = append(token.Modifiers, legend.TokenModifiers[bit])
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
@@ -0,0 +1,33 @@
+package lsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	legend := SemanticTokensLegend{
+		TokenTypes:     []string{"namespace", "function", "variable"},
+		TokenModifiers: []string{"declaration", "readonly"},
+	}
+	data := []int{
+		2, 1, 3, 0, 0, // line 2 char 1
+		0, 4, 7, 1, 0, // 同一行，相对上一个标记的起点
+		1, 2, 1, 2, 3, // 换行后起点为绝对值
+		0, 2, 1, 9, 4, // 未知类型与超出范围的修饰符
+		0, 1, // 不完整的分组被忽略
+	}
+	want := []SemanticToken{
+		{Line: 2, Character: 1, Length: 3, Type: "namespace"},
+		{Line: 2, Character: 5, Length: 7, Type: "function"},
+		{Line: 3, Character: 2, Length: 1, Type: "variable", Modifiers: []string{"declaration", "readonly"}},
+		{Line: 3, Character: 4, Length: 1},
+	}
+	got := DecodeSemanticTokens(data, legend)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DecodeSemanticTokens =\n%+v\nwant\n%+v", got, want)
+	}
+	if !got[2].HasModifier("readonly") || got[1].HasModifier("readonly") {
+		t.Error("HasModifier mismatch")
+	}
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PathToURI 将本地文件路径转换为 file URI，空格、非 ASCII 等字符会被正确编码
+func PathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	// Windows 盘符路径需要以 / 开头，如 file:///C:/Users
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// URIToPath 将 file URI 还原为本地文件路径
+func URIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("解析URI失败: %w", err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("不支持的URI协议: %s", uri)
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		// /C:/Users -> C:/Users
+		if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		if u.Host != "" {
+			// UNC 路径 file://server/share
+			path = "//" + u.Host + path
+		}
+	}
+	return filepath.FromSlash(path), nil
+}
@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPathToURI_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		escaped string
+	}{
+		{"main.go", "main.go"},
+		{"with space.go", "with%20space.go"},
+		{"100%.go", "100%25.go"},
+		{"中文.go", "%E4%B8%AD%E6%96%87.go"},
+		{"a#b?.go", "a%23b%3F.go"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		uri := PathToURI(path)
+		if !strings.HasPrefix(uri, "file:///") {
+			t.Errorf("PathToURI(%q) = %q, want file:/// prefix", path, uri)
+		}
+		if !strings.HasSuffix(uri, "/"+tt.escaped) {
+			t.Errorf("PathToURI(%q) = %q, want suffix %q", path, uri, tt.escaped)
+		}
+		got, err := URIToPath(uri)
+		if err != nil {
+			t.Fatalf("URIToPath(%q) error: %v", uri, err)
+		}
+		if got != path {
+			t.Errorf("URIToPath(%q) = %q, want %q", uri, got, path)
+		}
+	}
+}
+
+func TestURIToPath_WindowsDrive(t *testing.T) {
+	got, err := URIToPath("file:///C:/Users/me/with%20space.go")
+	if err != nil {
+		t.Fatalf("URIToPath error: %v", err)
+	}
+	want := "/C:/Users/me/with space.go"
+	if runtime.GOOS == "windows" {
+		want = `C:\Users\me\with space.go`
+	}
+	if got != want {
+		t.Errorf("URIToPath = %q, want %q", got, want)
+	}
+	if runtime.GOOS == "windows" {
+		if uri := PathToURI(`C:\Users\me\a b.go`); uri != "file:///C:/Users/me/a%20b.go" {
+			t.Errorf("PathToURI = %q", uri)
+		}
+	}
+}
+
+func TestURIToPath_Invalid(t *testing.T) {
+	if _, err := URIToPath("http://example.com/a.go"); err == nil {
+		t.Error("non-file scheme should fail")
+	}
+	if _, err := URIToPath("file://%zz"); err == nil {
+		t.Error("malformed uri should fail")
+	}
+}
+
+// Test: 服务端未声明支持时不修改本地工作区列表
+func TestAddWorkspaceFolder_Unsupported(t *testing.T) {
+	c := &LSPClient{}
+	err := c.AddWorkspaceFolder(context.Background(), t.TempDir())
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("AddWorkspaceFolder error = %v, want ErrUnsupported", err)
+	}
+	if folders := c.WorkspaceFolders(); len(folders) != 0 {
+		t.Errorf("WorkspaceFolders = %v, want empty", folders)
+	}
+}
+
+// versionRecorder 记录 didChange 通知中的版本号，写入较慢以放大乱序
+type versionRecorder struct {
+	mu       sync.Mutex
+	versions []int
+}
+
+func (r *versionRecorder) Write(p []byte) (int, error) {
+	var note struct {
+		Params struct {
+			TextDocument struct {
+				Version int `json:"version"`
+			} `json:"textDocument"`
+		} `json:"params"`
+	}
+	if i := bytes.Index(p, []byte("\r\n\r\n")); i >= 0 {
+		json.Unmarshal(p[i+4:], &note)
+	}
+	time.Sleep(time.Millisecond)
+	r.mu.Lock()
+	r.versions = append(r.versions, note.Params.TextDocument.Version)
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+func (r *versionRecorder) Close() error { return nil }
+
+// Test: 并发修改同一文档时 didChange 按版本号顺序发送
+func TestChangeDocument_OrderedVersions(t *testing.T) {
+	rec := &versionRecorder{}
+	c := &LSPClient{stdin: rec, documents: make(map[string]*document)}
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := c.OpenDocument(ctx, path, "go", ""); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.ChangeDocument(ctx, path, "package main")
+		}()
+	}
+	wg.Wait()
+	for i, v := range rec.versions {
+		if v != i+1 {
+			t.Fatalf("versions = %v, want increasing from 1", rec.versions)
+		}
+	}
+}
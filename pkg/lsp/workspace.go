@@ -0,0 +1,269 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	MethodDidChangeWorkspaceFolders = "workspace/didChangeWorkspaceFolders"
+	MethodDidChangeWatchedFiles     = "workspace/didChangeWatchedFiles"
+)
+
+// FileChangeType 对应 workspace/didChangeWatchedFiles 中的变更类型
+type FileChangeType int
+
+const (
+	FileCreated FileChangeType = 1
+	FileChanged FileChangeType = 2
+	FileDeleted FileChangeType = 3
+)
+
+type FileEvent struct {
+	URI  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type WorkspaceFoldersServerCapabilities struct {
+	Supported bool `json:"supported,omitempty"`
+	// ChangeNotifications 可能是 bool 或注册 ID 字符串
+	ChangeNotifications interface{} `json:"changeNotifications,omitempty"`
+}
+
+type WorkspaceServerCapabilities struct {
+	WorkspaceFolders *WorkspaceFoldersServerCapabilities `json:"workspaceFolders,omitempty"`
+}
+
+// NewWorkspaceFolder 根据本地目录创建工作区目录描述
+func NewWorkspaceFolder(path string) WorkspaceFolder {
+	return WorkspaceFolder{
+		URI:  PathToURI(path),
+		Name: filepath.Base(path),
+	}
+}
+
+// document 记录已打开文档的状态，版本号在每次变更时递增。
+// mu 保证同一文档的通知按版本顺序发送，version 与 text 的读写仍由 documentsMutex 保护
+type document struct {
+	mu         sync.Mutex
+	uri        string
+	languageID string
+	version    int
+	text       string
+	// closed 文档已发送 didClose，等待中的变更不再发送
+	closed bool
+}
+
+// OpenDocument 打开本地文件对应的文档。文档已打开时等同于 ChangeDocument。
+func (c *LSPClient) OpenDocument(ctx context.Context, path, languageID, text string) error {
+	uri := PathToURI(path)
+	for {
+		c.documentsMutex.Lock()
+		doc, ok := c.documents[uri]
+		if !ok {
+			doc = &document{
+				uri:        uri,
+				languageID: languageID,
+				version:    1,
+				text:       text,
+			}
+			// 文档加入列表前加锁，其他变更需等待 didOpen 发送后才能发送
+			doc.mu.Lock()
+			c.documents[uri] = doc
+			c.documentsMutex.Unlock()
+			defer doc.mu.Unlock()
+			return c.DidOpen(ctx, uri, languageID, 1, text)
+		}
+		c.documentsMutex.Unlock()
+
+		doc.mu.Lock()
+		if !doc.closed {
+			defer doc.mu.Unlock()
+			return c.changeDocument(doc, text)
+		}
+		// 文档正在关闭，关闭完成后重新打开
+		doc.mu.Unlock()
+	}
+}
+
+// ChangeDocument 以全量同步的方式更新已打开文档的内容，文档未打开时返回错误
+func (c *LSPClient) ChangeDocument(ctx context.Context, path, text string) error {
+	uri := PathToURI(path)
+	c.documentsMutex.RLock()
+	doc, ok := c.documents[uri]
+	c.documentsMutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("文档未打开: %s", uri)
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	if doc.closed {
+		return fmt.Errorf("文档未打开: %s", uri)
+	}
+	return c.changeDocument(doc, text)
+}
+
+// changeDocument 递增版本号并发送 didChange，调用方需持有 doc.mu
+func (c *LSPClient) changeDocument(doc *document, text string) error {
+	c.documentsMutex.Lock()
+	doc.version++
+	doc.text = text
+	version := doc.version
+	c.documentsMutex.Unlock()
+
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":     doc.uri,
+			"version": version,
+		},
+		"contentChanges": []map[string]interface{}{
+			{"text": text},
+		},
+	}
+	return c.sendNotification("textDocument/didChange", params)
+}
+
+// CloseDocument 关闭已打开的文档，didClose 发送后才从列表中移除，避免重新打开的 didOpen 先于 didClose 发送
+func (c *LSPClient) CloseDocument(ctx context.Context, path string) error {
+	uri := PathToURI(path)
+	c.documentsMutex.RLock()
+	doc, ok := c.documents[uri]
+	c.documentsMutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	if doc.closed {
+		return nil
+	}
+	doc.closed = true
+	params := map[string]interface{}{
+		"textDocument": TextDocumentIdentifier{URI: uri},
+	}
+	err := c.sendNotification("textDocument/didClose", params)
+	c.documentsMutex.Lock()
+	delete(c.documents, uri)
+	c.documentsMutex.Unlock()
+	return err
+}
+
+// DocumentText 返回已打开文档的最新内容
+func (c *LSPClient) DocumentText(uri string) (string, bool) {
+	c.documentsMutex.RLock()
+	defer c.documentsMutex.RUnlock()
+	doc, ok := c.documents[uri]
+	if !ok {
+		return "", false
+	}
+	return doc.text, true
+}
+
+// OpenDocuments 返回所有已打开文档的 URI
+func (c *LSPClient) OpenDocuments() []string {
+	c.documentsMutex.RLock()
+	defer c.documentsMutex.RUnlock()
+	uris := make([]string, 0, len(c.documents))
+	for uri := range c.documents {
+		uris = append(uris, uri)
+	}
+	return uris
+}
+
+// WorkspaceFolders 返回当前的工作区目录列表
+func (c *LSPClient) WorkspaceFolders() []WorkspaceFolder {
+	c.workspaceMutex.RLock()
+	defer c.workspaceMutex.RUnlock()
+	folders := make([]WorkspaceFolder, len(c.workspaceFolders))
+	copy(folders, c.workspaceFolders)
+	return folders
+}
+
+// AddWorkspaceFolder 添加工作区目录，已存在时忽略
+// 服务端不支持多工作区时返回 *UnsupportedError，本地列表保持不变
+func (c *LSPClient) AddWorkspaceFolder(ctx context.Context, path string) error {
+	if err := c.require(MethodDidChangeWorkspaceFolders); err != nil {
+		return err
+	}
+	folder := NewWorkspaceFolder(path)
+	c.workspaceMutex.Lock()
+	for _, f := range c.workspaceFolders {
+		if f.URI == folder.URI {
+			c.workspaceMutex.Unlock()
+			return nil
+		}
+	}
+	c.workspaceFolders = append(c.workspaceFolders, folder)
+	c.workspaceMutex.Unlock()
+	return c.didChangeWorkspaceFolders([]WorkspaceFolder{folder}, nil)
+}
+
+// RemoveWorkspaceFolder 移除工作区目录，不存在时忽略
+func (c *LSPClient) RemoveWorkspaceFolder(ctx context.Context, path string) error {
+	if err := c.require(MethodDidChangeWorkspaceFolders); err != nil {
+		return err
+	}
+	uri := PathToURI(path)
+	c.workspaceMutex.Lock()
+	var removed []WorkspaceFolder
+	folders := c.workspaceFolders[:0]
+	for _, f := range c.workspaceFolders {
+		if f.URI == uri {
+			removed = append(removed, f)
+			continue
+		}
+		folders = append(folders, f)
+	}
+	c.workspaceFolders = folders
+	c.workspaceMutex.Unlock()
+	if len(removed) == 0 {
+		return nil
+	}
+	return c.didChangeWorkspaceFolders(nil, removed)
+}
+
+func (c *LSPClient) didChangeWorkspaceFolders(added, removed []WorkspaceFolder) error {
+	if added == nil {
+		added = []WorkspaceFolder{}
+	}
+	if removed == nil {
+		removed = []WorkspaceFolder{}
+	}
+	params := map[string]interface{}{
+		"event": map[string]interface{}{
+			"added":   added,
+			"removed": removed,
+		},
+	}
+	return c.sendNotification(MethodDidChangeWorkspaceFolders, params)
+}
+
+// DidChangeWatchedFiles 通知服务端磁盘文件发生变化，如 REPL 写入的辅助文件或 go.mod
+func (c *LSPClient) DidChangeWatchedFiles(ctx context.Context, events ...FileEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	params := map[string]interface{}{
+		"changes": events,
+	}
+	return c.sendNotification(MethodDidChangeWatchedFiles, params)
+}
+
+// NotifyFileChanged 是 DidChangeWatchedFiles 针对本地路径的便捷方法
+func (c *LSPClient) NotifyFileChanged(ctx context.Context, typ FileChangeType, paths ...string) error {
+	events := make([]FileEvent, 0, len(paths))
+	for _, path := range paths {
+		events = append(events, FileEvent{URI: PathToURI(path), Type: typ})
+	}
+	if err := c.DidChangeWatchedFiles(ctx, events...); err != nil {
+		return fmt.Errorf("发送文件变更通知失败: %w", err)
+	}
+	return nil
+}
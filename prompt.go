@@ -1,9 +1,6 @@
 package prompt
 
 import (
-	"fmt"
-	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,21 +10,46 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/wxnacy/code-prompt/pkg/log"
+	"github.com/wxnacy/code-prompt/pkg/lsp"
 )
 
 type (
 	CompletionFunc       func(input string, cursor int) []CompletionItem
 	CompletionSelectFunc func(p *Prompt, input string, cursor int, selected CompletionItem)
+	CodeActionFunc       func(input string, cursor int) []CompletionItem
 	OutFunc              func(input string) string
+	// OutResultFunc 与 OutFunc 相同，但可以附带状态与元数据，设置后优先于 OutFunc
+	OutResultFunc func(input string) OutResult
+
+	// FeatureSupport 查询后端（如 LSP 服务端）是否支持某项功能，*lsp.LSPClient 已实现该接口
+	FeatureSupport interface {
+		Supports(method string) bool
+	}
 
 	EmptyMsg struct{}
 )
 
+// OutResult 单次执行的输出
+type OutResult struct {
+	Text string // 输出内容
+	// Status 显示在输出块底部的状态，如程序被终止的原因
+	Status string
+	// Meta 附加信息，记录到历史项中
+	Meta map[string]string
+}
+
 // HistoryItem 记录单条历史信息，遵循 zsh_history 的时间戳与耗时方案。
+// Cwd、SessionID 与 Meta 只有 JSONL 格式（NewJSONLHistoryStore）可以持久化。
 type HistoryItem struct {
-	Timestamp       int64  // 执行命令的时间戳（秒）
-	DurationSeconds int64  // 命令执行耗时（秒）
-	Command         string // 执行的命令内容
+	Timestamp       int64  `json:"timestamp"`         // 执行命令的时间戳（秒）
+	DurationSeconds int64  `json:"duration"`          // 命令执行耗时（秒）
+	Command         string `json:"command"`           // 执行的命令内容
+	Cwd             string `json:"cwd,omitempty"`     // 执行命令时的工作目录
+	SessionID       string `json:"session,omitempty"` // 执行命令的会话，见 Prompt.SessionID
+	// Meta 执行的附加信息，如程序终止原因、输出大小。
+	// zsh 历史格式无法保存 Cwd、SessionID 与 Meta，只保留在当前进程的内存中，
+	// 需要持久化（如按目录翻找其他进程的历史）时使用 NewJSONLHistoryStore 或 NewEncryptedHistoryStore
+	Meta map[string]string `json:"meta,omitempty"`
 }
 
 var logger = log.GetLogger()
@@ -38,13 +60,15 @@ func Empty() tea.Msg {
 
 func NewPrompt(opts ...Option) *Prompt {
 	m := &Prompt{
-		width:           100,
-		prompt:          ">>> ",
-		KeyMap:          DefaultPromptKeyMap(),
-		outFunc:         func(input string) string { return input },
-		historys:        make([]*History, 0),
-		historyItems:    make([]HistoryItem, 0),
-		historyFilePath: "",
+		width:        100,
+		prompt:       ">>> ",
+		KeyMap:       DefaultPromptKeyMap(),
+		outFunc:      func(input string) string { return input },
+		historys:     make([]*History, 0),
+		historyItems: make([]HistoryItem, 0),
+		sessionID:    newSessionID(),
+		redactFunc:   DefaultRedactor().Redact,
+		commands:     DefaultCommandRegistry(),
 	}
 	WithCompletionFunc(m.DefaultCompletionFunc)(m)
 	WithCompletionSelectFunc(DefaultCompletionSelectFunc)(m)
@@ -62,29 +86,54 @@ type Prompt struct {
 	prompt string
 
 	// history
-	historys        []*History
-	historyItems    []HistoryItem
-	historyIndex    int // 历史记录索引，等于 len(historyItems) 表示当前输入
-	historyFilePath string
-	historyMu       sync.Mutex
+	historys       []*History
+	historyItems   []HistoryItem
+	historyIndex   int // 历史记录索引，等于 len(historyItems) 表示当前输入
+	historyStore   HistoryStore
+	historyOptions HistoryOptions
+	historyScope   HistoryScope
+	sessionID      string
+	redactFunc     RedactFunc
+	historyMu      sync.Mutex
 
 	// completion
 	completionItems      []CompletionItem
 	completionFunc       CompletionFunc
 	completionSelectFunc CompletionSelectFunc
 	completion           *Completion
+	// completionSelectOverride 非空时表示当前列表不是补全（如快速修复），回车使用该方法
+	completionSelectOverride CompletionSelectFunc
+
+	// code action
+	codeActionFunc       CodeActionFunc
+	codeActionSelectFunc CompletionSelectFunc
 
 	// input
-	input *Input
+	input       *Input
+	highlighter Highlighter
+	theme       Theme
 
 	// out
-	outFunc OutFunc
+	outFunc       OutFunc
+	outResultFunc OutResultFunc
+	formatter     Formatter
+	// nextValue 下一次输入框的初始内容，由内置命令等在执行后回填
+	nextValue string
+
+	// feature
+	featureSupport FeatureSupport
+
+	// commands 内置命令
+	commands *CommandRegistry
+
+	// overlay 输入框下方的浮层，如帮助，打开时接管按键
+	overlay tea.Model
 
 	KeyMap PromptKeyMap
 }
 
 func (m Prompt) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, m.highlightCmd(m.Value()))
 }
 
 func (m Prompt) View() string {
@@ -95,7 +144,11 @@ func (m Prompt) View() string {
 		}
 	}
 	views = append(views, m.input.View())
-	views = append(views, m.GetCompletionView())
+	if m.overlay != nil {
+		views = append(views, m.overlay.View())
+	} else {
+		views = append(views, m.GetCompletionView())
+	}
 	view := lipgloss.JoinVertical(
 		lipgloss.Top,
 		views...,
@@ -111,8 +164,33 @@ func (m *Prompt) UpdateInput(msg tea.Msg) tea.Cmd {
 }
 
 func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	before := m.Value()
+	model, cmd := m.update(msg)
+	// 输入变化后异步获取高亮，避免在 View 中阻塞
+	if value := m.Value(); value != before {
+		if highlightCmd := m.highlightCmd(value); highlightCmd != nil {
+			cmd = tea.Batch(cmd, highlightCmd)
+		}
+	}
+	return model, cmd
+}
+
+// highlightCmd 高亮器支持异步获取时返回获取 value 高亮的命令
+func (m *Prompt) highlightCmd(value string) tea.Cmd {
+	if h, ok := m.highlighter.(AsyncHighlighter); ok {
+		return h.HighlightCmd(value)
+	}
+	return nil
+}
+
+func (m *Prompt) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
+	if overlayCmd, handled := m.updateOverlay(msg); handled {
+		return m, overlayCmd
+	} else if overlayCmd != nil {
+		cmds = append(cmds, overlayCmd)
+	}
 	switch msg := msg.(type) {
 	// 键位操作
 	case tea.KeyMsg:
@@ -125,8 +203,25 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// 没有输入数据的时候才退出，否则执行向后删除功能
 				return m, tea.Quit
 			}
+		case m.Value() == "" && key.Matches(msg, m.KeyMap.Help):
+			// 输入为空时才打开帮助，否则 ? 作为普通字符输入
+			return m, m.ShowHelp()
 		case key.Matches(msg, m.KeyMap.ClearCompletion):
 			m.completion = nil
+			m.completionSelectOverride = nil
+			return m, Empty
+		case key.Matches(msg, m.KeyMap.HistoryBrowser):
+			return m, m.ShowHistoryBrowser()
+		case key.Matches(msg, m.KeyMap.HistoryScope):
+			m.ToggleHistoryScope()
+			return m, Empty
+		case m.codeActionFunc != nil && key.Matches(msg, m.KeyMap.CodeAction):
+			// 列出当前输入可用的快速修复，等待诊断可能较慢，在后台获取
+			return m, m.showCodeActions()
+		case m.Value() != "" && key.Matches(msg, m.KeyMap.Format):
+			if err := m.formatInput(); err != nil {
+				logger.Warnf("格式化输入失败: %v", err)
+			}
 			return m, Empty
 		case key.Matches(msg, m.KeyMap.PrevHistory):
 			// 向上翻找记录
@@ -140,7 +235,8 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					idx          int
 				)
 				m.historyMu.Lock()
-				total := len(m.historyItems)
+				items := m.historyNavigation()
+				total := len(items)
 				if total == 0 {
 					m.historyIndex = 0
 				} else {
@@ -152,7 +248,7 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					} else {
 						m.historyIndex = 0
 					}
-					historyValue = m.historyItems[m.historyIndex].Command
+					historyValue = items[m.historyIndex].Command
 					hasHistory = true
 				}
 				idx = m.historyIndex
@@ -178,13 +274,14 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					idx          int
 				)
 				m.historyMu.Lock()
-				total := len(m.historyItems)
+				items := m.historyNavigation()
+				total := len(items)
 				switch {
 				case total == 0:
 					m.historyIndex = 0
 				case m.historyIndex < total-1:
 					m.historyIndex++
-					historyValue = m.historyItems[m.historyIndex].Command
+					historyValue = items[m.historyIndex].Command
 					hasHistory = true
 				case total > 0:
 					m.historyIndex = total
@@ -222,30 +319,37 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// 如果有补全建议，使用选中的，或者开始的第一个
 				selected := m.completion.GetSelected()
 				// 触发选择补全的方法
-				if m.completionSelectFunc != nil {
-					m.completionSelectFunc(m, value, m.Cursor(), selected)
+				selectFunc := m.completionSelectFunc
+				if m.completionSelectOverride != nil {
+					selectFunc = m.completionSelectOverride
+				}
+				if selectFunc != nil {
+					selectFunc(m, value, m.Cursor(), selected)
 				}
 				m.completion = nil
+				m.completionSelectOverride = nil
 			} else {
-				out := value
-				// 检查是否需要执行快速命令（如：!11）。命中后拦截并返回。
-				if m.handleBangQuickExec(value) {
+				// 检查是否需要展开历史（如：!!、!11）。展开后拦截并等待确认。
+				var expanded bool
+				if value, expanded = m.handleHistoryExpansion(value); expanded {
 					return m, Empty
 				}
 				// 进行输出
+				var result OutResult
 				execStart := time.Now()
-				if builtionFunc, exists := IsMatchBuiltinCommandFunc(value); exists {
-					out, cmd = builtionFunc(m, value)
+				if command, exists := m.lookupCommand(value); exists {
+					result.Text, cmd = command.Exec(m, value)
 					cmds = append(cmds, cmd)
 				} else {
-					if m.outFunc != nil {
-						out = m.outFunc(value)
-					}
+					// 执行前格式化，保证执行内容与历史记录一致
+					value = m.formatValue(value)
+					result = m.execOut(value)
 				}
 				duration := time.Since(execStart)
-				m.AppendHistory(value, out)
-				m.AppendHistoryItem(value, execStart, duration)
+				m.appendHistoryResult(value, result)
+				m.appendHistoryItem(value, execStart, duration, historyMeta(result))
 				m.input = m.NewInput()
+				m.applyNextValue()
 			}
 			return m, tea.Batch(cmds...)
 		}
@@ -257,7 +361,8 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 			m.completion = completion.(*Completion)
 
-			if key.Matches(msg, m.completion.KeyMap.NextCompletion, m.completion.KeyMap.PrevCompletion) {
+			// 快速修复等列表只在回车时应用，切换选项时不预览
+			if m.completionSelectOverride == nil && key.Matches(msg, m.completion.KeyMap.NextCompletion, m.completion.KeyMap.PrevCompletion) {
 				selected := m.completion.GetSelected()
 				// 触发选择补全的方法
 				if m.completionSelectFunc != nil {
@@ -274,6 +379,15 @@ func (m *Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		// 组件键位监听 end
 		return m, tea.Batch(cmds...)
+	case codeActionsMsg:
+		m.handleCodeActions(msg)
+		return m, tea.Batch(cmds...)
+	case HistorySelectedMsg:
+		// 仅填入输入框，不直接执行
+		m.completion = nil
+		m.SetValue(msg.Command)
+		m.SetCursor(len(msg.Command))
+		return m, tea.Batch(cmds...)
 	}
 
 	// 处理列表和输入框的其他消息
@@ -284,10 +398,48 @@ func (m *Prompt) Width(w int) {
 	m.width = w
 }
 
+// Commands 返回当前 Prompt 的内置命令注册表
+func (m *Prompt) Commands() *CommandRegistry {
+	return m.commands
+}
+
+// lookupCommand 查找内置命令，Prompt 自己的命令优先，没有时查找通过包级方法注册的命令
+func (m *Prompt) lookupCommand(value string) (Command, bool) {
+	if cmd, ok := m.commands.Lookup(value); ok {
+		return cmd, true
+	}
+	return legacyCommands.Lookup(value)
+}
+
+// commandCompletions 返回内置命令的补全列表，兼容通过包级方法注册的命令
+func (m *Prompt) commandCompletions() []CompletionItem {
+	return commandCompletionItems(m.allCommands())
+}
+
 func (m *Prompt) OutFunc(f OutFunc) {
 	WithOutFunc(f)(m)
 }
 
+// OutResultFunc 设置带状态与元数据的输出方法
+func (m *Prompt) OutResultFunc(f OutResultFunc) {
+	WithOutResultFunc(f)(m)
+}
+
+// Formatter 设置执行前的格式化器
+func (m *Prompt) Formatter(f Formatter) {
+	WithFormatter(f)(m)
+}
+
+// Highlighter 设置输入框的语法高亮
+func (m *Prompt) Highlighter(h Highlighter) {
+	WithHighlighter(h)(m)
+}
+
+// FeatureSupport 设置功能查询对象，后端不支持的功能对应的快捷键会被隐藏
+func (m *Prompt) FeatureSupport(fs FeatureSupport) {
+	WithFeatureSupport(fs)(m)
+}
+
 // Completion begin =============
 
 func (m *Prompt) Completions(items []CompletionItem) {
@@ -323,6 +475,7 @@ func (m Prompt) GetCompletionView() string {
 // - 优先使用内置函数补全，如果补全到信息直接返回
 // - 然后进行正常补全逻辑
 func (m *Prompt) handleCompletion(input string, cursor int) {
+	m.completionSelectOverride = nil
 	setCompletion := func(items []CompletionItem) {
 		if items != nil && len(items) > 0 {
 			m.completion = NewCompletion(items)
@@ -333,8 +486,16 @@ func (m *Prompt) handleCompletion(input string, cursor int) {
 
 	// 优先使用内置函数补全
 	if strings.HasPrefix(input, "/") {
+		// 已输入命令名时补全命令参数
+		if items, ok := m.commandArgCompletions(input, cursor); ok {
+			setCompletion(items)
+			if m.completion != nil {
+				m.completionSelectOverride = commandArgSelectFunc
+			}
+			return
+		}
 		// 处理内置方法补全
-		builtinCompletionItems := GetBuiltinCommandCompletions()
+		builtinCompletionItems := m.commandCompletions()
 		if len(builtinCompletionItems) > 0 {
 			items := simpleCompletion(builtinCompletionItems, input, cursor)
 			setCompletion(items)
@@ -347,8 +508,8 @@ func (m *Prompt) handleCompletion(input string, cursor int) {
 	// 走到这里说明内置函数没有获取到补全信息
 
 	// 进行正常补全
-	// 使用补全方法获取自全列表
-	if m.completionFunc != nil {
+	// 使用补全方法获取自全列表，后端不支持补全时不再请求
+	if m.completionFunc != nil && m.supports(lsp.MethodCompletion) {
 		newCompletionItems := m.completionFunc(input, cursor)
 		if newCompletionItems != nil && len(newCompletionItems) > 0 {
 			m.completion = NewCompletion(newCompletionItems)
@@ -358,6 +519,44 @@ func (m *Prompt) handleCompletion(input string, cursor int) {
 	}
 }
 
+// showCodeActions 以补全列表的形式展示快速修复，回车时应用选中项
+// codeActionsMsg 后台获取的快速修复列表
+type codeActionsMsg struct {
+	input string
+	items []CompletionItem
+}
+
+// showCodeActions 返回在后台获取快速修复的命令，结果通过 codeActionsMsg 展示
+func (m *Prompt) showCodeActions() tea.Cmd {
+	if m.codeActionFunc == nil {
+		return nil
+	}
+	list := m.codeActionFunc
+	input, cursor := m.Value(), m.Cursor()
+	return func() tea.Msg {
+		return codeActionsMsg{input: input, items: list(input, cursor)}
+	}
+}
+
+// handleCodeActions 展示快速修复列表，获取期间输入已变化时丢弃结果
+func (m *Prompt) handleCodeActions(msg codeActionsMsg) {
+	if msg.input != m.Value() {
+		return
+	}
+	if len(msg.items) == 0 {
+		m.completion = nil
+		m.completionSelectOverride = nil
+		return
+	}
+	m.completion = NewCompletion(msg.items)
+	m.completionSelectOverride = m.codeActionSelectFunc
+}
+
+// CodeActions 设置快速修复的列表与应用方法
+func (m *Prompt) CodeActions(list CodeActionFunc, apply CompletionSelectFunc) {
+	WithCodeActions(list, apply)(m)
+}
+
 // Completion end   =============
 
 // Input begin ==================
@@ -365,6 +564,9 @@ func (m *Prompt) handleCompletion(input string, cursor int) {
 func (m Prompt) NewInput() *Input {
 	input := NewInput()
 	input.Model.Prompt = m.prompt
+	input.Highlighter = m.highlighter
+	input.Theme = m.theme
+	input.Model.Placeholder = m.historyScopePlaceholder()
 	return input
 }
 
@@ -384,6 +586,52 @@ func (m Prompt) SetCursor(pos int) {
 	m.input.Model.SetCursor(pos)
 }
 
+// SetNextValue 设置本次执行结束后新输入框的初始内容，用于内置命令回填输入
+func (m *Prompt) SetNextValue(s string) {
+	m.nextValue = s
+}
+
+func (m *Prompt) applyNextValue() {
+	if m.nextValue == "" {
+		return
+	}
+	m.SetValue(m.nextValue)
+	m.SetCursor(len(m.nextValue))
+	m.nextValue = ""
+}
+
+// formatValue 使用格式化器处理输入，格式化失败时保留原始输入，并同步更新输入框
+func (m *Prompt) formatValue(value string) string {
+	if m.formatter == nil {
+		return value
+	}
+	formatted, err := m.formatter.Format(value)
+	if err != nil {
+		logger.Debugf("格式化输入失败: %v", err)
+		return value
+	}
+	if formatted != value {
+		m.SetValue(formatted)
+		m.SetCursor(len(formatted))
+	}
+	return formatted
+}
+
+// formatInput 格式化输入框中的内容，未设置格式化器时使用 GoFormatter
+func (m *Prompt) formatInput() error {
+	var formatter Formatter = NewGoFormatter()
+	if m.formatter != nil {
+		formatter = m.formatter
+	}
+	formatted, err := formatter.Format(m.Value())
+	if err != nil {
+		return err
+	}
+	m.SetValue(formatted)
+	m.SetCursor(len(formatted))
+	return nil
+}
+
 // Input end   ==================
 
 // History begin ================
@@ -392,95 +640,138 @@ func (m *Prompt) HistoryFile(p string) {
 	WithHistoryFile(p)(m)
 }
 
-// AppendHistory 将历史命令和输出添加到 View 中
+// HistoryStore 设置历史后端
+func (m *Prompt) HistoryStore(store HistoryStore) {
+	WithHistoryStore(store)(m)
+}
+
+// AppendHistory 将历史命令和输出添加到 View 中，敏感信息会被脱敏
 func (m *Prompt) AppendHistory(command string, outText string) {
-	out := NewOut(outText)
-	if outText == "" {
-		out = nil
+	m.appendHistoryResult(command, OutResult{Text: outText})
+}
+
+// appendHistoryResult 将当前输入与输出结果添加到 View 中，输入与输出都会先脱敏，
+// command 为执行的命令，用于匹配脱敏的放行规则
+func (m *Prompt) appendHistoryResult(command string, result OutResult) {
+	if value := m.Value(); value != "" {
+		if redacted := m.redact(command, value); redacted != value {
+			m.SetValue(redacted)
+		}
+	}
+	var out *Out
+	if result.Text != "" || result.Status != "" {
+		out = NewOut(m.redact(command, result.Text))
+		out.Status = result.Status
 	}
 	h := NewHistory(m.input, out)
 	m.historys = append(m.historys, h)
 }
 
-// AppendHistoryItem 将历史项写入内存，并同步到历史文件中。
+// execOut 执行输入并返回输出，优先使用 outResultFunc
+func (m *Prompt) execOut(value string) OutResult {
+	if m.outResultFunc != nil {
+		return m.outResultFunc(value)
+	}
+	if m.outFunc != nil {
+		return OutResult{Text: m.outFunc(value)}
+	}
+	return OutResult{Text: value}
+}
+
+// AppendHistoryItem 将历史项写入内存，并同步到历史文件中，命令中的敏感信息会被脱敏。
 func (m *Prompt) AppendHistoryItem(command string, startedAt time.Time, duration time.Duration) {
-	trimmed := strings.TrimSpace(command)
-	if trimmed == "" {
-		// 仅记录包含有效字符的历史项
+	m.appendHistoryItem(command, startedAt, duration, nil)
+}
+
+func (m *Prompt) appendHistoryItem(command string, startedAt time.Time, duration time.Duration, meta map[string]string) {
+	command, ok := m.historyCommand(command)
+	if !ok {
 		return
 	}
 	item := HistoryItem{
 		Timestamp:       startedAt.Unix(),
 		DurationSeconds: int64(duration / time.Second),
 		Command:         command,
+		Cwd:             workingDir(),
+		SessionID:       m.sessionID,
+		Meta:            meta,
 	}
 	if item.DurationSeconds < 0 {
 		item.DurationSeconds = 0
 	}
 	m.historyMu.Lock()
-	m.historyItems = append(m.historyItems, item)
+	items, added := m.historyOptions.add(m.historyItems, item, m.historyOptions.MaxEntries)
+	m.historyItems = items
 	m.historyIndex = len(m.historyItems)
 	m.historyMu.Unlock()
+	if !added {
+		return
+	}
 
-	if err := m.appendHistoryToFile(item); err != nil {
+	if err := m.appendHistoryToStore(item); err != nil {
 		logger.Warnf("写入历史文件失败: %v", err)
 	}
 }
 
-// appendHistoryToFile 将历史记录安全追加到文件中。
-func (m *Prompt) appendHistoryToFile(item HistoryItem) error {
-	if m.historyFilePath == "" {
-		return nil
-	}
-	path := m.historyFilePath
-	if err := ensureHistoryFile(path); err != nil {
-		return err
-	}
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
-	if err != nil {
-		return err
+// historyCommand 返回脱敏后记录到历史中的命令，仅记录包含有效字符且未被选项忽略的命令
+func (m *Prompt) historyCommand(command string) (string, bool) {
+	if m.historyOptions.ignored(command) {
+		return "", false
 	}
-	defer file.Close()
-
-	if err := lockFile(file); err != nil {
-		return err
+	if m.historyOptions.IgnoreBuiltins {
+		if _, ok := m.lookupCommand(command); ok {
+			return "", false
+		}
 	}
-	defer func() {
-		_ = unlockFile(file)
-	}()
+	return m.redact(command, command), true
+}
 
-	if _, err := file.WriteString(formatHistoryItem(item)); err != nil {
-		return err
+// appendHistoryToStore 将历史记录写入历史后端。
+func (m *Prompt) appendHistoryToStore(item HistoryItem) error {
+	if m.historyStore == nil {
+		return nil
 	}
-	return file.Sync()
+	return m.historyStore.Append(item, m.historyOptions)
 }
 
-// refreshHistoryItemsFromFile 刷新内存中的历史记录。
+// refreshHistoryItemsFromFile 从历史后端刷新内存中的历史记录，只合并其他写入方新增的内容，
+// 历史文件被替换或重写时完整重载。
 func (m *Prompt) refreshHistoryItemsFromFile() error {
-	if m.historyFilePath == "" {
+	if m.historyStore == nil {
 		return nil
 	}
-	items, err := readHistoryFile(m.historyFilePath)
+	items, reload, err := m.historyStore.Load()
 	if err != nil {
 		return err
 	}
+	if !reload && len(items) == 0 {
+		return nil
+	}
 	m.historyMu.Lock()
-	if len(items) >= len(m.historyItems) {
-		m.historyItems = items
-		if m.historyIndex > len(m.historyItems) {
-			m.historyIndex = len(m.historyItems)
+	defer m.historyMu.Unlock()
+	// 当前位于输入行时，刷新后依然停留在输入行
+	atInput := m.historyIndex >= len(m.historyItems)
+	if reload {
+		items = restoreHistoryTags(items, m.historyItems)
+		m.historyItems = trimHistoryItems(items, m.historyOptions.MaxEntries)
+	} else {
+		for _, item := range items {
+			m.historyItems, _ = m.historyOptions.add(m.historyItems, item, m.historyOptions.MaxEntries)
 		}
 	}
-	m.historyMu.Unlock()
+	if atInput || m.historyIndex > len(m.historyItems) {
+		m.historyIndex = len(m.historyItems)
+	}
 	return nil
 }
 
-// handleBangQuickExec 处理以 ! 开头的快速历史执行语法（如：!11）。
-// 返回值表示是否已拦截处理该输入（true 表示已处理，外层无需继续执行）。
-func (m *Prompt) handleBangQuickExec(value string) bool {
-	// 非 bang 语法，直接透传
-	if !strings.HasPrefix(value, "!") {
-		return false
+// handleHistoryExpansion 展开输入中的历史引用（如：!!、!11、!$、^old^new），语法见 ExpandHistory。
+// 发生展开时仅回填输入框等待确认，返回 true 表示已拦截处理该输入（外层无需继续执行）；
+// 否则返回移除转义后的输入供外层执行。
+func (m *Prompt) handleHistoryExpansion(value string) (string, bool) {
+	// 不含展开语法，直接透传
+	if !strings.Contains(value, "!") && !strings.HasPrefix(value, "^") {
+		return value, false
 	}
 
 	// 同步最新历史，避免并发或多进程写入导致的视图滞后
@@ -488,38 +779,26 @@ func (m *Prompt) handleBangQuickExec(value string) bool {
 		logger.Warnf("同步历史失败: %v", err)
 	}
 
-	// 去除前缀并裁剪空格
-	cmdNumString := strings.TrimSpace(strings.TrimPrefix(value, "!"))
-	if cmdNumString == "" {
-		out := fmt.Sprintf("wgo: no such event: %s", cmdNumString)
-		m.AppendHistory(value, out)
-		m.input = m.NewInput()
-		return true
+	m.historyMu.Lock()
+	history := make([]string, len(m.historyItems))
+	for i, item := range m.historyItems {
+		history[i] = item.Command
 	}
+	m.historyMu.Unlock()
 
-	// 仅允许十进制数字，其他情况按不存在处理
-	n, err := strconv.Atoi(cmdNumString)
+	expanded, changed, err := ExpandHistory(value, history)
 	if err != nil {
-		out := fmt.Sprintf("wgo: no such event: %s", cmdNumString)
-		m.AppendHistory(value, out)
+		m.AppendHistory(value, err.Error())
 		m.input = m.NewInput()
-		return true
+		return value, true
 	}
-
-	// 在锁内读取历史并设置输入
-	m.historyMu.Lock()
-	defer m.historyMu.Unlock()
-	if n > -1 && n < len(m.historyItems) {
-		historyCmd := m.historyItems[n].Command
-		// 仅将输入框替换为历史命令，不直接执行
-		m.SetValue(historyCmd)
-		m.SetCursor(len(historyCmd))
-	} else {
-		out := fmt.Sprintf("wgo: no such event: %s", cmdNumString)
-		m.AppendHistory(value, out)
-		m.input = m.NewInput()
+	if !changed {
+		return expanded, false
 	}
-	return true
+	// 仅将输入框替换为展开后的命令，不直接执行
+	m.SetValue(expanded)
+	m.SetCursor(len(expanded))
+	return expanded, true
 }
 
 // History end   ================
@@ -539,17 +818,33 @@ func WithWidth(w int) Option {
 	}
 }
 
-// WithHistoryFile 设置历史文件路径，允许覆盖默认地址。
+// WithHistoryFile 设置历史文件路径，允许覆盖默认地址。历史文件使用 zsh 扩展格式，
+// 等同于 WithHistoryStore(NewZshHistoryStore(path))，path 为空时不保存历史。
 func WithHistoryFile(path string) Option {
 	return func(p *Prompt) {
-		resolved, err := resolveHistoryFilePath(path)
+		if strings.TrimSpace(path) == "" {
+			WithHistoryStore(nil)(p)
+			return
+		}
+		store, err := NewZshHistoryStore(path)
 		if err != nil {
 			logger.Warnf("设置历史文件失败: %v", err)
 			return
 		}
-		p.historyFilePath = resolved
+		WithHistoryStore(store)(p)
+	}
+}
+
+// WithHistoryStore 设置历史后端并加载历史，store 为 nil 时不保存历史。
+// Prompt 使用 store.Open() 得到的后端，因此同一个 store 可以设置给多个 Prompt。
+func WithHistoryStore(store HistoryStore) Option {
+	return func(p *Prompt) {
+		p.historyStore = nil
+		if store != nil {
+			p.historyStore = store.Open()
+		}
 		if err := p.refreshHistoryItemsFromFile(); err != nil {
-			logger.Warnf("加载历史文件失败: %v", err)
+			logger.Warnf("加载历史失败: %v", err)
 		}
 		p.historyMu.Lock()
 		p.historyIndex = len(p.historyItems)
@@ -563,12 +858,76 @@ func WithCompletions(items []CompletionItem) Option {
 	}
 }
 
+// WithCommandRegistry 设置内置命令注册表，替换默认的 /history、/fmt、/help、/exit
+func WithCommandRegistry(r *CommandRegistry) Option {
+	return func(p *Prompt) {
+		p.commands = r
+	}
+}
+
 func WithOutFunc(f OutFunc) Option {
 	return func(p *Prompt) {
 		p.outFunc = f
 	}
 }
 
+// WithOutResultFunc 设置带状态与元数据的输出方法，设置后优先于 OutFunc
+func WithOutResultFunc(f OutResultFunc) Option {
+	return func(p *Prompt) {
+		p.outResultFunc = f
+	}
+}
+
+// WithCodeActions 设置快速修复：list 列出当前输入可用的修复项，apply 应用选中项。
+// 使用 LSP 时可传入 NewLSPCodeActions(...) 的 List 与 Apply。
+func WithCodeActions(list CodeActionFunc, apply CompletionSelectFunc) Option {
+	return func(p *Prompt) {
+		p.codeActionFunc = list
+		p.codeActionSelectFunc = apply
+	}
+}
+
+// WithFormatter 设置执行前的格式化器，格式化结果会用于执行并写入历史，
+// 如 NewGoFormatter() 或 NewLSPFormatter()
+func WithFormatter(f Formatter) Option {
+	return func(p *Prompt) {
+		p.formatter = f
+	}
+}
+
+// WithHighlighter 设置输入框的语法高亮，如 NewGoHighlighter() 或 NewLSPHighlighter()
+func WithHighlighter(h Highlighter) Option {
+	return func(p *Prompt) {
+		p.highlighter = h
+		if p.input != nil {
+			p.input.Highlighter = h
+		}
+	}
+}
+
+// WithTheme 设置语法高亮主题，未设置时使用 DefaultTheme
+func WithTheme(t Theme) Option {
+	return func(p *Prompt) {
+		p.theme = t
+		if p.input != nil {
+			p.input.Theme = t
+		}
+	}
+}
+
+// supports 判断后端是否支持指定的 LSP 方法，未设置功能查询对象时视为全部支持
+func (m *Prompt) supports(method string) bool {
+	return m.featureSupport == nil || m.featureSupport.Supports(method)
+}
+
+// WithFeatureSupport 设置功能查询对象，并据此启用或隐藏依赖该功能的快捷键
+func WithFeatureSupport(fs FeatureSupport) Option {
+	return func(p *Prompt) {
+		p.featureSupport = fs
+		p.KeyMap.ApplyFeatureSupport(fs)
+	}
+}
+
 func WithCompletionFunc(f CompletionFunc) Option {
 	return func(p *Prompt) {
 		p.completionFunc = f
@@ -2,6 +2,7 @@ package prompt
 
 import (
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/wxnacy/code-prompt/pkg/lsp"
 )
 
 func DefaultPromptKeyMap() PromptKeyMap {
@@ -18,6 +19,14 @@ func DefaultPromptKeyMap() PromptKeyMap {
 			key.WithKeys("ctrl+x"),
 			key.WithHelp("ctrl+x", "取消补全建议"),
 		),
+		CodeAction: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "快速修复"),
+		),
+		Format: key.NewBinding(
+			key.WithKeys("alt+F"),
+			key.WithHelp("alt+shift+f", "格式化输入"),
+		),
 		NextCompletion: defaultCompletionKeyMap.NextCompletion,
 		PrevCompletion: defaultCompletionKeyMap.PrevCompletion,
 		NextHistory: key.NewBinding(
@@ -28,6 +37,14 @@ func DefaultPromptKeyMap() PromptKeyMap {
 			key.WithKeys("up", "ctrl+p"),
 			key.WithHelp("↑/ctrl+p", "下一条历史"),
 		),
+		HistoryBrowser: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "浏览历史"),
+		),
+		HistoryScope: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "切换历史范围"),
+		),
 		Clear: key.NewBinding(
 			key.WithKeys("ctrl+l"),
 			key.WithHelp("ctrl+l", "清屏"),
@@ -36,6 +53,10 @@ func DefaultPromptKeyMap() PromptKeyMap {
 			key.WithKeys("ctrl+g"),
 			key.WithHelp("ctrl+g", "放弃当前命令"),
 		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "帮助（输入为空时）"),
+		),
 	}
 }
 
@@ -44,10 +65,14 @@ type PromptKeyMap struct {
 	NextCompletion  key.Binding // ShortHelp ListenKeys
 	PrevCompletion  key.Binding // ShortHelp ListenKeys
 	ClearCompletion key.Binding // ShortHelp ListenKeys
+	CodeAction      key.Binding // ListenKeys
+	Format          key.Binding // ListenKeys
 
 	// FullHelp
-	NextHistory key.Binding // ListenKeys
-	PrevHistory key.Binding // ListenKeys
+	NextHistory    key.Binding // ListenKeys
+	PrevHistory    key.Binding // ListenKeys
+	HistoryBrowser key.Binding // ListenKeys
+	HistoryScope   key.Binding // ListenKeys
 
 	// FullHelp
 	Clear  key.Binding // ListenKeys
@@ -56,6 +81,7 @@ type PromptKeyMap struct {
 	// FullHelp
 	Enter key.Binding // ListenKeys
 	Exit  key.Binding // ListenKeys
+	Help  key.Binding // ListenKeys
 }
 
 func (km PromptKeyMap) ShortHelp() []key.Binding {
@@ -65,10 +91,10 @@ func (km PromptKeyMap) ShortHelp() []key.Binding {
 // FullHelp 返回所有快捷键的帮助信息。
 func (km PromptKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{km.NextCompletion, km.PrevCompletion, km.ClearCompletion},
-		{km.NextHistory, km.PrevHistory},
+		{km.NextCompletion, km.PrevCompletion, km.ClearCompletion, km.CodeAction, km.Format},
+		{km.NextHistory, km.PrevHistory, km.HistoryBrowser, km.HistoryScope},
 		{km.Clear, km.GiveUp},
-		{km.Exit, km.Enter},
+		{km.Exit, km.Enter, km.Help},
 	}
 }
 
@@ -78,11 +104,35 @@ func (km PromptKeyMap) ListenKeys() []key.Binding {
 		km.NextCompletion,
 		km.PrevCompletion,
 		km.ClearCompletion,
+		km.CodeAction,
+		km.Format,
 		km.NextHistory,
 		km.PrevHistory,
+		km.HistoryBrowser,
+		km.HistoryScope,
 		km.Clear,
 		km.GiveUp,
 		km.Enter,
 		km.Exit,
+		km.Help,
+	}
+}
+
+// featureBindings 返回依赖后端功能的快捷键，key 为对应的 LSP 方法名。
+// 补全导航快捷键同时服务于内置命令补全，不随后端补全能力禁用
+func (km *PromptKeyMap) featureBindings() map[string][]*key.Binding {
+	return map[string][]*key.Binding{
+		lsp.MethodCodeAction: {&km.CodeAction},
+	}
+}
+
+// ApplyFeatureSupport 根据后端支持情况启用或禁用快捷键，禁用的快捷键不会响应也不会出现在帮助中。
+// fs 为 nil 时视为全部支持。
+func (km *PromptKeyMap) ApplyFeatureSupport(fs FeatureSupport) {
+	for method, bindings := range km.featureBindings() {
+		enabled := fs == nil || fs.Supports(method)
+		for _, binding := range bindings {
+			binding.SetEnabled(enabled)
+		}
 	}
 }
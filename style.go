@@ -8,3 +8,35 @@ var BaseStyle = lipgloss.NewStyle().
 
 var BaseFocusStyle = BaseStyle.
 	BorderForeground(lipgloss.AdaptiveColor{Light: "#EE6FF8", Dark: "#EE6FF8"})
+
+// OutStatusStyle 输出块底部状态的样式
+var OutStatusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Italic(true)
+
+// Theme 语法高亮主题，按标记类型映射样式
+type Theme map[TokenKind]lipgloss.Style
+
+// DefaultTheme 默认高亮主题
+func DefaultTheme() Theme {
+	return Theme{
+		TokenKeyword:   lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true),
+		TokenString:    lipgloss.NewStyle().Foreground(lipgloss.Color("114")),
+		TokenNumber:    lipgloss.NewStyle().Foreground(lipgloss.Color("215")),
+		TokenComment:   lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Italic(true),
+		TokenOperator:  lipgloss.NewStyle().Foreground(lipgloss.Color("252")),
+		TokenFunction:  lipgloss.NewStyle().Foreground(lipgloss.Color("75")),
+		TokenType:      lipgloss.NewStyle().Foreground(lipgloss.Color("80")),
+		TokenParameter: lipgloss.NewStyle().Foreground(lipgloss.Color("223")).Italic(true),
+		TokenVariable:  lipgloss.NewStyle(),
+		TokenNamespace: lipgloss.NewStyle().Foreground(lipgloss.Color("141")),
+		TokenConstant:  lipgloss.NewStyle().Foreground(lipgloss.Color("215")),
+		TokenProperty:  lipgloss.NewStyle().Foreground(lipgloss.Color("153")),
+	}
+}
+
+// Style 返回标记类型对应的样式，未配置时返回空样式
+func (t Theme) Style(kind TokenKind) lipgloss.Style {
+	if style, ok := t[kind]; ok {
+		return style
+	}
+	return lipgloss.NewStyle()
+}